@@ -31,7 +31,8 @@ func main() {
 
 	dg.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMembers | discordgo.IntentsMessageContent |
 		discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages |
-		discordgo.IntentsGuildMessageReactions | discordgo.IntentsGuildVoiceStates
+		discordgo.IntentsGuildMessageReactions | discordgo.IntentsGuildVoiceStates |
+		discordgo.IntentsGuildMessageTyping
 
 	if err := handler.PollerInit(dg); err != nil {
 		fmt.Println("Error initializing poller:", err)
@@ -62,18 +63,58 @@ func main() {
 		return
 	}
 
+	if err := handler.CharacterInit(dg); err != nil {
+		fmt.Println("Error initializing character:", err)
+		return
+	}
+	defer handler.CharacterFinalize()
+
+	if err := handler.LevelTrackerInit(dg); err != nil {
+		fmt.Println("Error initializing level tracker:", err)
+		return
+	}
+	defer handler.LevelTrackerFinalize()
+
+	if err := handler.GuildCompositionInit(dg); err != nil {
+		fmt.Println("Error initializing guild composition:", err)
+		return
+	}
+
+	if err := handler.InfoBoardInit(); err != nil {
+		fmt.Println("Error initializing info board:", err)
+		return
+	}
+	defer handler.InfoBoardFinalize()
+
 	if err := handler.RegisterRaidCommands(dg); err != nil {
 		fmt.Println("Error registering raid commands:", err)
 		return
 	}
 	defer handler.UnregisterCommands(dg)
 
+	if err := handler.RegisterActivityCommand(dg); err != nil {
+		fmt.Println("Error registering activity command:", err)
+		return
+	}
+
 	if err := handler.RaidInit(dg); err != nil {
 		fmt.Println("Error initializing raid:", err)
 		return
 	}
 	defer handler.RaidFinalize()
 
+	if environment.RaidCalendarHTTPAddr != "" {
+		if err := handler.RaidCalendarServe(environment.RaidCalendarHTTPAddr); err != nil {
+			fmt.Println("Error serving raid calendar:", err)
+			return
+		}
+		defer func() {
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer shutdownCancel()
+			handler.RaidCalendarShutdown(shutdownCtx)
+		}()
+	}
+
 	if err := handler.ModUserInit(dg); err != nil {
 		fmt.Println("Error initializing mod user:", err)
 		return
@@ -114,6 +155,9 @@ func main() {
 			if err := handler.RaidSubscriptionRefresh(dg); err != nil {
 				fmt.Println("Error refreshing raid subscription:", err)
 			}
+			if err := handler.RaidReminderRefresh(dg); err != nil {
+				fmt.Println("Error sending raid reminders:", err)
+			}
 			if err := handler.HandlePersistLastActivityTime(); err != nil {
 				fmt.Println("Error handling persist last activity time:", err)
 			}
@@ -125,20 +169,34 @@ func main() {
 			if err := handler.PollFinishChecker(dg); err != nil {
 				fmt.Println("Error checking poll finish:", err)
 			}
+			if err := handler.InactivityCheckInRefresh(dg); err != nil {
+				fmt.Println("Error refreshing inactivity check-ins:", err)
+			}
 		case <-littleMidTermTicker.C:
 			if err := handler.RaidRoleMappingRefresh(dg); err != nil {
 				fmt.Println("Error refreshing raid role mapping:", err)
 			}
+			if err := handler.RaidScheduleTemplateRefresh(dg); err != nil {
+				fmt.Println("Error refreshing raid schedule templates:", err)
+			}
+			if err := handler.RaidWeeklyReport(dg); err != nil {
+				fmt.Println("Error posting weekly raid report:", err)
+			}
+			if err := handler.LevelMonthlyReport(dg); err != nil {
+				fmt.Println("Error posting monthly level report:", err)
+			}
+			if err := handler.ActivityWeeklyDigest(dg); err != nil {
+				fmt.Println("Error posting weekly activity digest:", err)
+			}
 		case <-midTermTicker.C:
-			err := handler.UpdateMessageWithRoles(dg,
-				environment.DiscordGuildInfoChannelID, []string{environment.DiscordGuildInfoByRoleMessageID, environment.DiscordGuildInfoByRoleMessageID2})
-			if err != nil {
-				fmt.Println("Error updating message with roles:", err)
+			if err := handler.UpdateGuildInfoBoards(dg, environment.DiscordGuildInfoChannelID); err != nil {
+				fmt.Println("Error updating guild info boards:", err)
 			}
-			err = handler.UpdateMessagesWithLevels(dg,
-				environment.DiscordGuildInfoChannelID, []string{environment.DiscordGuildInfoByLevelMessageID, environment.DiscordGuildInfoByLevelMessageID2})
-			if err != nil {
-				fmt.Println("Error updating messages with levels:", err)
+			if err := handler.LevelSnapshotRefresh(dg); err != nil {
+				fmt.Println("Error recording level snapshots:", err)
+			}
+			if err := handler.GuildCompositionSnapshotRefresh(); err != nil {
+				fmt.Println("Error recording guild composition:", err)
 			}
 		case <-longTermTicker.C:
 			err = handler.GeneralizeUsername(dg, environment.DiscordGuildID)
@@ -0,0 +1,94 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event is a single VEVENT of the calendar.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+}
+
+const (
+	timeZoneID   = "Asia/Seoul"
+	maxLineBytes = 75
+)
+
+// Render renders the events as an RFC 5545 iCalendar document with times in Asia/Seoul.
+func Render(name string, events []Event) []byte {
+	loc, err := time.LoadLocation(timeZoneID)
+	if err != nil {
+		loc = time.FixedZone("KST", 9*60*60)
+	}
+	now := time.Now().UTC().Format("20060102T150405Z")
+
+	var sb strings.Builder
+	writeLine(&sb, "BEGIN:VCALENDAR")
+	writeLine(&sb, "VERSION:2.0")
+	writeLine(&sb, "PRODID:-//eternity-bot//raid schedule//KO")
+	writeLine(&sb, "CALSCALE:GREGORIAN")
+	writeLine(&sb, "METHOD:PUBLISH")
+	writeLine(&sb, "X-WR-CALNAME:"+escapeText(name))
+	writeLine(&sb, "X-WR-TIMEZONE:"+timeZoneID)
+
+	// Asia/Seoul has no daylight saving time
+	writeLine(&sb, "BEGIN:VTIMEZONE")
+	writeLine(&sb, "TZID:"+timeZoneID)
+	writeLine(&sb, "BEGIN:STANDARD")
+	writeLine(&sb, "DTSTART:19700101T000000")
+	writeLine(&sb, "TZOFFSETFROM:+0900")
+	writeLine(&sb, "TZOFFSETTO:+0900")
+	writeLine(&sb, "TZNAME:KST")
+	writeLine(&sb, "END:STANDARD")
+	writeLine(&sb, "END:VTIMEZONE")
+
+	for _, e := range events {
+		writeLine(&sb, "BEGIN:VEVENT")
+		writeLine(&sb, "UID:"+e.UID)
+		writeLine(&sb, "DTSTAMP:"+now)
+		writeLine(&sb, fmt.Sprintf("DTSTART;TZID=%s:%s", timeZoneID, e.Start.In(loc).Format("20060102T150405")))
+		writeLine(&sb, fmt.Sprintf("DTEND;TZID=%s:%s", timeZoneID, e.End.In(loc).Format("20060102T150405")))
+		writeLine(&sb, "SUMMARY:"+escapeText(e.Summary))
+		if e.Description != "" {
+			writeLine(&sb, "DESCRIPTION:"+escapeText(e.Description))
+		}
+		writeLine(&sb, "END:VEVENT")
+	}
+
+	writeLine(&sb, "END:VCALENDAR")
+	return []byte(sb.String())
+}
+
+func escapeText(text string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(text)
+}
+
+// writeLine writes a content line terminated by CRLF, folding it at 75 octets
+// without splitting a multi-byte character.
+func writeLine(sb *strings.Builder, line string) {
+	limit := maxLineBytes
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space
+		limit = maxLineBytes - 1
+	}
+	sb.WriteString(line)
+	sb.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,88 @@
+package calendar
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var dtstamp = regexp.MustCompile(`DTSTAMP:\d{8}T\d{6}Z`)
+
+func TestRender(t *testing.T) {
+	start := time.Date(2026, time.October, 21, 12, 0, 0, 0, time.UTC)
+	got := string(Render("레이드", []Event{
+		{UID: "a@test", Summary: "자쿰", Start: start, End: start.Add(time.Hour)},
+		{UID: "b@test", Summary: "[혼테일]; 1트라이", Description: "첫 줄\n둘째, 줄\\", Start: start, End: start},
+	}))
+	got = dtstamp.ReplaceAllString(got, "DTSTAMP:-")
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//eternity-bot//raid schedule//KO",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:레이드",
+		"X-WR-TIMEZONE:Asia/Seoul",
+		"BEGIN:VTIMEZONE",
+		"TZID:Asia/Seoul",
+		"BEGIN:STANDARD",
+		"DTSTART:19700101T000000",
+		"TZOFFSETFROM:+0900",
+		"TZOFFSETTO:+0900",
+		"TZNAME:KST",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:a@test",
+		"DTSTAMP:-",
+		"DTSTART;TZID=Asia/Seoul:20261021T210000",
+		"DTEND;TZID=Asia/Seoul:20261021T220000",
+		"SUMMARY:자쿰",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:b@test",
+		"DTSTAMP:-",
+		"DTSTART;TZID=Asia/Seoul:20261021T210000",
+		"DTEND;TZID=Asia/Seoul:20261021T210000",
+		`SUMMARY:[혼테일]\; 1트라이`,
+		`DESCRIPTION:첫 줄\n둘째\, 줄\\`,
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteLineFolds(t *testing.T) {
+	for _, line := range []string{
+		"SUMMARY:자쿰",
+		strings.Repeat("a", maxLineBytes),
+		"DESCRIPTION:" + strings.Repeat("a", 200),
+		"DESCRIPTION:" + strings.Repeat("레이드", 40),
+	} {
+		var sb strings.Builder
+		writeLine(&sb, line)
+		folded := sb.String()
+		if !strings.HasSuffix(folded, "\r\n") {
+			t.Fatalf("line is not CRLF terminated: %q", folded)
+		}
+
+		parts := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+		for idx, part := range parts {
+			if len(part) > maxLineBytes {
+				t.Errorf("%.20q: part %d is %d octets long", line, idx, len(part))
+			}
+			if idx > 0 && !strings.HasPrefix(part, " ") {
+				t.Errorf("%.20q: continuation %d does not start with a space", line, idx)
+			}
+		}
+		// unfolding is removing every CRLF followed by a space
+		if unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", ""); unfolded != line {
+			t.Errorf("unfolded %q, want %q", unfolded, line)
+		}
+	}
+}
@@ -94,7 +94,7 @@ func SendNewRaidModal(s *discordgo.Session, i *discordgo.Interaction) {
 	}
 }
 
-func SendNewRaidScheduleModal(s *discordgo.Session, i *discordgo.Interaction, raidID string) {
+func SendNewRaidScheduleModal(s *discordgo.Session, i *discordgo.Interaction, raidID string, tryCount int) {
 	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseModal,
 		Data: &discordgo.InteractionResponseData{
@@ -130,12 +130,36 @@ func SendNewRaidScheduleModal(s *discordgo.Session, i *discordgo.Interaction, ra
 						discordgo.TextInput{
 							CustomID:    "try-count",
 							Label:       "트라이",
+							Value:       strconv.Itoa(tryCount),
 							Placeholder: "1",
 							Required:    true,
 							Style:       discordgo.TextInputShort,
 						},
 					},
 				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "capacity",
+							Label:       "정원 (0은 제한 없음)",
+							Placeholder: "30",
+							Value:       "0",
+							Required:    true,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "job-capacity",
+							Label:       "직업별 정원",
+							Placeholder: "비숍:2,전사:6 (생략 가능)",
+							Required:    false,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
 			},
 		},
 	})
@@ -190,6 +214,106 @@ func SendEditRaidScheduleModal(s *discordgo.Session, i *discordgo.Interaction, s
 						},
 					},
 				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "capacity",
+							Label:       "정원 (0은 제한 없음)",
+							Value:       strconv.Itoa(schedule.Capacity),
+							Placeholder: "30",
+							Required:    true,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "job-capacity",
+							Label:       "직업별 정원",
+							Value:       schedule.JobCapacity,
+							Placeholder: "비숍:2,전사:6 (생략 가능)",
+							Required:    false,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func SendRaidScheduleTemplateModal(s *discordgo.Session, i *discordgo.Interaction, title string, customID string, weekday string, startClock string, tryCount int, closeOffsetHours int, capacity int) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			Title:    title,
+			CustomID: customID,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "weekday",
+							Label:       "요일",
+							Placeholder: "월, 화, 수, 목, 금, 토, 일",
+							Value:       weekday,
+							Required:    true,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "start-clock",
+							Label:       "시작 시간",
+							Placeholder: "21:00",
+							Value:       startClock,
+							Required:    true,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "try-count",
+							Label:       "트라이",
+							Placeholder: "1",
+							Value:       strconv.Itoa(tryCount),
+							Required:    true,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "subscription-close-offset",
+							Label:       "모집 마감 (시작 몇 시간 전)",
+							Placeholder: "2",
+							Value:       strconv.Itoa(closeOffsetHours),
+							Required:    true,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "capacity",
+							Label:       "정원 (0은 제한 없음)",
+							Placeholder: "30",
+							Value:       strconv.Itoa(capacity),
+							Required:    true,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
 			},
 		},
 	})
@@ -225,6 +349,111 @@ func SendAdminAddAttendeeModal(s *discordgo.Session, i *discordgo.Interaction, s
 	}
 }
 
+func SendAddRaidManagerModal(s *discordgo.Session, i *discordgo.Interaction, raidID string) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			Title:    "공동 관리자 추가",
+			CustomID: "add-raid-manager-modal_" + raidID,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID: "nickname",
+							Label:    "닉네임",
+							Required: true,
+							Style:    discordgo.TextInputShort,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func SendRaidAttendNoteModal(s *discordgo.Session, i *discordgo.Interaction, scheduleID string, preference string, characterID string) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			Title:    "레이드 참가 신청",
+			CustomID: fmt.Sprintf("raid-join-modal_%s_%s_%s", scheduleID, preference, characterID),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "note",
+							Label:       "메모 (선택)",
+							Placeholder: "예) 부캐 비숍 가능, 30분 늦게 합류",
+							Required:    false,
+							MaxLength:   50,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func SendCharacterModal(s *discordgo.Session, i *discordgo.Interaction) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			Title:    "캐릭터 등록",
+			CustomID: "character-modal",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "nickname",
+							Label:       "닉네임",
+							Placeholder: "이미 등록한 닉네임이면 직업과 레벨이 수정됩니다.",
+							Required:    true,
+							MaxLength:   20,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "job",
+							Label:       "직업",
+							Placeholder: "히어로, 비숍, 나이트로드 등",
+							Required:    true,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "level",
+							Label:       "레벨",
+							Placeholder: "120",
+							Required:    true,
+							MaxLength:   3,
+							Style:       discordgo.TextInputShort,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
 func SendAdminRaidInfoResponse(s *discordgo.Session, i *discordgo.Interaction, schedule model.RaidSchedule, info model.RaidInfo, attendCount int) error {
 	// raid record
 	msg := fmt.Sprintf("**[%s] %s (%d 트라이) 레이드 기록**\n\n", schedule.Raid.RaidName, schedule.StartTime.Format("2006-01-02 15:04"), schedule.TryCount)
@@ -244,6 +473,16 @@ func SendAdminRaidInfoResponse(s *discordgo.Session, i *discordgo.Interaction, s
 		msg += fmt.Sprintf("* 종료: %s\n", info.EndTime.Format("2006-01-02 15:04"))
 	}
 	msg += fmt.Sprintf("* 참가자: %d명", attendCount)
+	if len(info.Attendances) > 0 {
+		counts := make(map[string]int)
+		for _, a := range info.Attendances {
+			counts[a.Status]++
+		}
+		msg += fmt.Sprintf("\n* 출석 체크: %s %d명 / %s %d명 / %s %d명",
+			model.RaidAttendancePresent, counts[model.RaidAttendancePresent],
+			model.RaidAttendanceAbsent, counts[model.RaidAttendanceAbsent],
+			model.RaidAttendanceWalkIn, counts[model.RaidAttendanceWalkIn])
+	}
 
 	s.InteractionRespond(i, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseUpdateMessage,
@@ -272,6 +511,20 @@ func SendAdminRaidInfoResponse(s *discordgo.Session, i *discordgo.Interaction, s
 							Style:    discordgo.SecondaryButton,
 							CustomID: fmt.Sprintf("admin-info-party-formation_%d", info.ID),
 						},
+						discordgo.Button{
+							Label:    "분배 관리",
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("admin-info-distribution_%d", info.ID),
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "출석 확인",
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("admin-info-attendance_%d", info.ID),
+						},
 					},
 				},
 			},
@@ -281,6 +534,107 @@ func SendAdminRaidInfoResponse(s *discordgo.Session, i *discordgo.Interaction, s
 	return nil
 }
 
+func SendNewDistributionRuleModal(s *discordgo.Session, i *discordgo.Interaction, infoID string) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			Title:    "분배 규칙 추가",
+			CustomID: "add-distribution-rule-modal_" + infoID,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "rule-name",
+							Label:       "규칙 이름",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "자쿰 기본 분배",
+							Required:    true,
+							MaxLength:   30,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "distribution-type",
+							Label:       "분배 방식",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "균등, 역할비율, 고정",
+							Required:    true,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "amount",
+							Label:       "비율 또는 고정 금액 (균등 분배는 0)",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "역할비율 1.5, 고정 1,000,000",
+							Value:       "0",
+							Required:    true,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "target-party-role",
+							Label:       "대상 파티 역할",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "전방, 메인딜, 서브딜, 예비 (생략 가능)",
+							Required:    false,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "target-level",
+							Label:       "대상 최소 레벨",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "120 (생략 가능)",
+							Required:    false,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func SendRaidLootModal(s *discordgo.Session, i *discordgo.Interaction, info model.RaidInfo) {
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			Title:    "판매 금액 입력",
+			CustomID: fmt.Sprintf("raid-loot-modal_%d", info.ID),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "loot-amount",
+							Label:       "총 판매 금액 (메소)",
+							Style:       discordgo.TextInputShort,
+							Placeholder: "150000000",
+							Value:       strconv.FormatInt(info.LootAmount, 10),
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
 func SendCounselModal(s *discordgo.Session, i *discordgo.Interaction) {
 	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseModal,
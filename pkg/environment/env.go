@@ -7,15 +7,32 @@ var (
 	DiscordGuildID = lookupEnv("DISCORD_GUILD_ID", "fake")
 
 	DiscordGuildInfoChannelID             = lookupEnv("DISCORD_GI_CHANNEL_ID", "fake")
-	DiscordGuildInfoByRoleMessageID       = lookupEnv("DISCORD_GIBR_MESSAGE_ID", "fake")
-	DiscordGuildInfoByRoleMessageID2      = lookupEnv("DISCORD_GIBR_MESSAGE_ID2", "fake")
-	DiscordGuildInfoByLevelMessageID      = lookupEnv("DISCORD_GIBL_MESSAGE_ID", "fake")
-	DiscordGuildInfoByLevelMessageID2     = lookupEnv("DISCORD_GIBL_MESSAGE_ID2", "fake")
 	DiscordCounselChannelID               = lookupEnv("DISCORD_COUNSEL_CHANNEL_ID", "fake")
 	DiscordGuildPollChannelID             = lookupEnv("DISCORD_GP_CHANNEL_ID", "fake")
 	DiscordGuildRaidSubscriptionChannelID = lookupEnv("DISCORD_GRSC_CHANNEL_ID", "fake")
 	DiscordGuildRaidManageChannelID       = lookupEnv("DISCORD_GRMC_CHANNEL_ID", "fake")
 	DiscordGuildRaidInfoChannelID         = lookupEnv("DISCORD_GRI_CHANNEL_ID", "fake")
+	DiscordGuildRaidVoiceChannelID        = lookupEnv("DISCORD_GRV_CHANNEL_ID", "fake")
+	DiscordGuildRaidAdminRoleID           = lookupEnv("DISCORD_GRA_ROLE_ID", "fake")
+	DiscordGuildLevelUpChannelID          = lookupEnv("DISCORD_GLU_CHANNEL_ID", "")
+	DiscordGuildActivityDigestChannelID   = lookupEnv("DISCORD_GAD_CHANNEL_ID", "")
+
+	RaidReminderOffsets            = lookupEnv("RAID_REMINDER_OFFSETS", "1h,10m")
+	RaidSubscriptionReminderOffset = lookupEnv("RAID_SUBSCRIPTION_REMINDER_OFFSET", "3h")
+
+	RaidCalendarHTTPAddr = lookupEnv("RAID_CALENDAR_HTTP_ADDR", "")
+	RaidCalendarBaseURL  = lookupEnv("RAID_CALENDAR_BASE_URL", "")
+
+	InactivityCheckInGracePeriod = lookupEnv("INACTIVITY_CHECKIN_GRACE_PERIOD", "72h")
+	InactivityAutoCheckInDays    = lookupEnv("INACTIVITY_AUTO_CHECKIN_DAYS", "")
+
+	// the messages the info boards were edited into before the board messages were stored,
+	// they are taken over in this order on the first update and the ones no longer needed are deleted.
+	// they can be unset once the boards have been updated.
+	DiscordGuildInfoByRoleMessageID   = lookupEnv("DISCORD_GIBR_MESSAGE_ID", "")
+	DiscordGuildInfoByRoleMessageID2  = lookupEnv("DISCORD_GIBR_MESSAGE_ID2", "")
+	DiscordGuildInfoByLevelMessageID  = lookupEnv("DISCORD_GIBL_MESSAGE_ID", "")
+	DiscordGuildInfoByLevelMessageID2 = lookupEnv("DISCORD_GIBL_MESSAGE_ID2", "")
 
 	NotionBotAPIKey   = lookupEnv("NOTION_BOT_API_KEY", "fake")
 	NotionCounselDBID = lookupEnv("NOTION_COUNSEL_DB_ID", "fake")
@@ -24,6 +41,8 @@ var (
 	ActivitySQLiteDBPath   = lookupEnv("ACTIVITY_SQLITE_DB_PATH", "activity.db")
 	RaidSQLiteDBPath       = lookupEnv("RAID_SQLITE_DB_PATH", "raid.db")
 	LevelTrackerSQLitePath = lookupEnv("LEVEL_TRACKER_SQLITE_PATH", "leveltracking.db")
+	CharacterSQLiteDBPath  = lookupEnv("CHARACTER_SQLITE_DB_PATH", "character.db")
+	InfoBoardSQLiteDBPath  = lookupEnv("INFO_BOARD_SQLITE_DB_PATH", "infoboard.db")
 )
 
 func lookupEnv(key string, def string) string {
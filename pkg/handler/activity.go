@@ -36,6 +36,31 @@ func ActivityInit(dg *discordgo.Session) error {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	err = adb.AutoMigrate(&model.InactivityCheckIn{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = adb.AutoMigrate(&model.ActivityCounter{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = adb.AutoMigrate(&model.VoiceSession{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = adb.AutoMigrate(&model.ActivityHourCounter{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = adb.AutoMigrate(&model.ActivityLeaderboardOptOut{}, &model.ActivityDigestPost{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	// add watchers
 	dg.AddHandler(onMessageCreate)
 	dg.AddHandler(onMessageUpdate)
@@ -43,6 +68,11 @@ func ActivityInit(dg *discordgo.Session) error {
 	dg.AddHandler(onReactionRemove)
 	dg.AddHandler(onVoiceStateUpdate)
 	dg.AddHandler(onTypingStart)
+	dg.AddHandler(inactivityHandler)
+	dg.AddHandler(activityCommandHandler)
+	dg.AddHandler(onVoiceGuildCreate)
+	dg.AddHandler(voiceHandler)
+	dg.AddHandler(activityOptOutHandler)
 
 	return nil
 }
@@ -57,6 +87,15 @@ func ActivityFinalize() {
 }
 
 func HandlePersistLastActivityTime() error {
+	// the heartbeat counts the voice time so far, flushed along with the other counters
+	if err := VoiceSessionHeartbeat(); err != nil {
+		return err
+	}
+	// counters first, so a member missing from the cache below does not hold them back
+	if err := flushActivityCounters(); err != nil {
+		return err
+	}
+
 	lock.Lock()
 	defer lock.Unlock()
 
@@ -116,17 +155,30 @@ func updateGuildActivity(userID string) {
 func onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 	if !m.Author.Bot {
 		updateGuildActivity(m.Author.ID)
+		if m.GuildID != "" {
+			countActivity(m.Author.ID, m.ChannelID, model.ActivityKindMessage)
+		}
 	}
 }
 
 func onMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	// embeds resolving also fire updates, without the author
+	if m.Author == nil {
+		return
+	}
 	if !m.Author.Bot {
 		updateGuildActivity(m.Author.ID)
+		if m.GuildID != "" {
+			countActivity(m.Author.ID, m.ChannelID, model.ActivityKindEdit)
+		}
 	}
 }
 
 func onReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
 	updateGuildActivity(r.UserID)
+	if r.GuildID != "" && r.UserID != s.State.User.ID {
+		countActivity(r.UserID, r.ChannelID, model.ActivityKindReaction)
+	}
 }
 
 func onReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
@@ -134,11 +186,20 @@ func onReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove)
 }
 
 func onVoiceStateUpdate(s *discordgo.Session, vs *discordgo.VoiceStateUpdate) {
-	// vs.BeforeUpdate와 vs.AfterUpdate를 비교하거나,
-	// 간단히 vs.VoiceState.ChannelID != "" 로 입장/이동 시점 체크
 	updateGuildActivity(vs.UserID)
+	if vs.GuildID != environment.DiscordGuildID {
+		return
+	}
+	if vs.Member != nil && vs.Member.User != nil && vs.Member.User.Bot {
+		return
+	}
+	// an empty channel is a leave, a different one from the open session is a move
+	trackVoiceSession(vs.UserID, vs.ChannelID, time.Now().UTC())
 }
 
 func onTypingStart(s *discordgo.Session, t *discordgo.TypingStart) {
 	updateGuildActivity(t.UserID)
+	if t.GuildID != "" {
+		countActivity(t.UserID, t.ChannelID, model.ActivityKindTyping)
+	}
 }
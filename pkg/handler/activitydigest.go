@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// activityDigestLines is how many members and channels each digest section shows.
+	activityDigestLines = 10
+	// activityDigestBaselineWeeks is how many weeks before the digest week tell the new and the quiet members apart.
+	activityDigestBaselineWeeks = 4
+	// activityDigestPeakHours is how many of the busiest hours the digest shows.
+	activityDigestPeakHours = 3
+)
+
+// listLeaderboardOptOuts returns the ids of the members who opted out of the public leaderboards.
+func listLeaderboardOptOuts() map[string]bool {
+	var optOuts []model.ActivityLeaderboardOptOut
+	adb.Find(&optOuts)
+
+	users := make(map[string]bool)
+	for _, o := range optOuts {
+		users[o.DiscordUserID] = true
+	}
+	return users
+}
+
+// activityHiddenFrom reports whether the member opted out and the records are viewed by someone else.
+func activityHiddenFrom(userID, viewerID string) bool {
+	return userID != viewerID && listLeaderboardOptOuts()[userID]
+}
+
+func setLeaderboardOptOut(userID string, optOut bool) error {
+	if !optOut {
+		return adb.Unscoped().Where("discord_user_id = ?", userID).Delete(&model.ActivityLeaderboardOptOut{}).Error
+	}
+	if listLeaderboardOptOuts()[userID] {
+		return nil
+	}
+	return adb.Create(&model.ActivityLeaderboardOptOut{DiscordUserID: userID}).Error
+}
+
+// publicActivityMembers returns the members of the grouped totals who are in the guild and did not opt out, most active first.
+func publicActivityMembers(grouped map[string]activityStats, optOuts map[string]bool) []string {
+	var users []string
+	for _, userID := range sortedActivityKeys(grouped) {
+		if optOuts[userID] || grouped[userID].score() == 0 || cache.GetGuildMember(userID) == nil {
+			continue
+		}
+		users = append(users, userID)
+	}
+	return users
+}
+
+func joinMemberNames(users []string) string {
+	var names []string
+	for idx, userID := range users {
+		if idx >= activityDigestLines {
+			names = append(names, fmt.Sprintf("외 %d명", len(users)-idx))
+			break
+		}
+		names = append(names, memberDisplayName(userID))
+	}
+	return strings.Join(names, ", ")
+}
+
+// renderActivityDigest renders the activity of the week starting on since, compared with the weeks before.
+func renderActivityDigest(since time.Time) string {
+	until := since.AddDate(0, 0, 6)
+	sinceDate, untilDate := activityDate(since), activityDate(until)
+	baselineSince := activityDate(since.AddDate(0, 0, -7*activityDigestBaselineWeeks))
+	baselineUntil := activityDate(since.AddDate(0, 0, -1))
+	optOuts := listLeaderboardOptOuts()
+
+	msg := fmt.Sprintf("**[주간 활동 리포트] %s ~ %s**\n", since.Format("01월 02일"), until.Format("01월 02일"))
+
+	week := groupActivityTotals(sumActivityCounters("discord_user_id", "", sinceDate, untilDate))
+	baseline := groupActivityTotals(sumActivityCounters("discord_user_id", "", baselineSince, baselineUntil))
+	active := publicActivityMembers(week, optOuts)
+
+	msg += "\n**가장 활발한 길드원**\n"
+	if len(active) == 0 {
+		msg += "기록된 활동이 없습니다.\n"
+	}
+	for idx, userID := range active {
+		if idx >= activityDigestLines {
+			break
+		}
+		msg += fmt.Sprintf("%d. %s - %s\n", idx+1, memberDisplayName(userID), week[userID].describe())
+	}
+
+	// active this week without any activity in the weeks before
+	var newlyActive []string
+	for _, userID := range active {
+		if baseline[userID].score() == 0 {
+			newlyActive = append(newlyActive, userID)
+		}
+	}
+	if len(newlyActive) > 0 {
+		msg += fmt.Sprintf("\n**새로 활동을 시작한 길드원** %d명\n%s\n", len(newlyActive), joinMemberNames(newlyActive))
+	}
+
+	// active in the weeks before without any activity this week
+	var quiet []string
+	for _, userID := range publicActivityMembers(baseline, optOuts) {
+		if week[userID].score() == 0 {
+			quiet = append(quiet, userID)
+		}
+	}
+	if len(quiet) > 0 {
+		msg += fmt.Sprintf("\n**이번 주 조용했던 길드원** %d명\n%s\n", len(quiet), joinMemberNames(quiet))
+	}
+
+	byChannel := groupActivityTotals(sumActivityCounters("channel_id", "", sinceDate, untilDate))
+	if len(byChannel) > 0 {
+		msg += "\n**가장 붐빈 채널**\n"
+		for idx, ch := range sortedActivityKeys(byChannel) {
+			if idx >= activityDigestLines/2 {
+				break
+			}
+			msg += fmt.Sprintf("%d. <#%s> - %s\n", idx+1, ch, byChannel[ch].describe())
+		}
+	}
+
+	type hourTotal struct {
+		Hour  int
+		Total int
+	}
+	var hours []hourTotal
+	adb.Model(&model.ActivityHourCounter{}).
+		Select("hour, SUM(count) AS total").
+		Where("date >= ? AND date <= ?", sinceDate, untilDate).
+		Group("hour").Scan(&hours)
+	sort.SliceStable(hours, func(i, j int) bool {
+		if hours[i].Total == hours[j].Total {
+			return hours[i].Hour < hours[j].Hour
+		}
+		return hours[i].Total > hours[j].Total
+	})
+	if len(hours) > 0 {
+		var peaks []string
+		for idx, h := range hours {
+			if idx >= activityDigestPeakHours {
+				break
+			}
+			peaks = append(peaks, fmt.Sprintf("%02d시~%02d시 (%d회)", h.Hour, (h.Hour+1)%24, h.Total))
+		}
+		msg += "\n**가장 붐빈 시간대 (KST)**\n" + strings.Join(peaks, ", ") + "\n"
+	}
+
+	msg += "\n`/활동순위공개` 명령어로 리포트와 순위, 다른 길드원의 조회에 이름이 나오지 않도록 설정할 수 있습니다.\n"
+	return msg
+}
+
+// ActivityWeeklyDigest posts the digest of last week's activity to the digest channel once a week, on Monday morning.
+func ActivityWeeklyDigest(dg *discordgo.Session) error {
+	if environment.DiscordGuildActivityDigestChannelID == "" {
+		return nil
+	}
+
+	now := time.Now().In(loc)
+	if now.Weekday() != time.Monday || now.Hour() < 9 {
+		return nil
+	}
+
+	until := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	since := until.AddDate(0, 0, -7)
+	week := since.Format("2006-01-02")
+
+	var count int64
+	adb.Model(&model.ActivityDigestPost{}).Where("week = ?", week).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	if err := sendSplitMessage(dg, environment.DiscordGuildActivityDigestChannelID, renderActivityDigest(since)); err != nil {
+		return fmt.Errorf("failed to send weekly activity digest: %w", err)
+	}
+	adb.Create(&model.ActivityDigestPost{Week: week})
+	return nil
+}
+
+func activityOptOutHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	data, ok := i.Data.(discordgo.ApplicationCommandInteractionData)
+	if !ok || data.Name != "활동순위공개" {
+		return
+	}
+
+	public := true
+	for _, opt := range data.Options {
+		if opt.Name == "공개" {
+			public = opt.BoolValue()
+		}
+	}
+
+	userID := interactionUserID(i)
+	if err := setLeaderboardOptOut(userID, !public); err != nil {
+		fmt.Println("failed to update leaderboard opt-out:", err)
+		respondEphemeral(s, i, "설정을 저장하지 못했습니다. 잠시 후 다시 시도해주세요.")
+		return
+	}
+
+	if public {
+		respondEphemeral(s, i, "주간 활동 리포트와 순위에 이름이 표시되고, 다른 길드원이 내 활동 기록을 볼 수 있습니다.")
+	} else {
+		respondEphemeral(s, i, "주간 활동 리포트와 순위에서 이름이 제외되고, 다른 길드원이 내 활동 기록을 볼 수 없습니다. 활동 기록은 그대로 남습니다.")
+	}
+}
@@ -0,0 +1,419 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// activityReportDays is the period the activity summaries cover.
+	activityReportDays = 30
+	// activityTrendDays is how many recent days are shown day by day.
+	activityTrendDays = 7
+	// activityTopChannels is how many channels the summaries show.
+	activityTopChannels = 10
+)
+
+type activityCounterKey struct {
+	Date      string
+	UserID    string
+	ChannelID string
+	Kind      string
+}
+
+type activityHourKey struct {
+	Date string
+	Hour int
+}
+
+// pending counters are kept in memory and upserted by the 15 second flush
+var pendingActivityCounters = make(map[activityCounterKey]int)
+var pendingActivityHours = make(map[activityHourKey]int)
+var activityCounterLock = &sync.Mutex{}
+
+func activityDate(t time.Time) string {
+	return t.In(loc).Format("2006-01-02")
+}
+
+func countActivity(userID, channelID, kind string) {
+	activityCounterLock.Lock()
+	defer activityCounterLock.Unlock()
+	now := time.Now()
+	pendingActivityCounters[activityCounterKey{activityDate(now), userID, channelID, kind}]++
+	pendingActivityHours[activityHourKey{activityDate(now), now.In(loc).Hour()}]++
+}
+
+// countVoiceSeconds adds the voice seconds of a session since it was last seen, the voice sessions are the source of the voice counters.
+func countVoiceSeconds(vs model.VoiceSession, now time.Time) {
+	seconds := int(now.Sub(vs.LastSeenAt).Seconds())
+	if seconds <= 0 {
+		return
+	}
+	activityCounterLock.Lock()
+	defer activityCounterLock.Unlock()
+	pendingActivityCounters[activityCounterKey{activityDate(now), vs.DiscordUserID, vs.ChannelID, model.ActivityKindVoice}] += seconds
+}
+
+// flushActivityCounters upserts the pending counters.
+func flushActivityCounters() error {
+	activityCounterLock.Lock()
+	now := time.Now()
+	pending := pendingActivityCounters
+	pendingActivityCounters = make(map[activityCounterKey]int)
+	pendingHours := pendingActivityHours
+	pendingActivityHours = make(map[activityHourKey]int)
+	activityCounterLock.Unlock()
+
+	// both are flushed either way, each puts its own counts back when it fails
+	hoursErr := flushActivityHours(pendingHours, now)
+	if len(pending) == 0 {
+		return hoursErr
+	}
+
+	rows := make([]model.ActivityCounter, 0, len(pending))
+	for k, n := range pending {
+		rows = append(rows, model.ActivityCounter{
+			Date:          k.Date,
+			DiscordUserID: k.UserID,
+			ChannelID:     k.ChannelID,
+			Kind:          k.Kind,
+			Count:         n,
+		})
+	}
+
+	err := adb.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "date"}, {Name: "discord_user_id"}, {Name: "channel_id"}, {Name: "kind"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":      gorm.Expr(`"count" + excluded."count"`),
+			"updated_at": now,
+		}),
+	}).CreateInBatches(&rows, 100).Error
+	if err != nil {
+		// keep the counts for the next flush
+		activityCounterLock.Lock()
+		for k, n := range pending {
+			pendingActivityCounters[k] += n
+		}
+		activityCounterLock.Unlock()
+		return errors.Join(fmt.Errorf("failed to flush activity counters: %w", err), hoursErr)
+	}
+	return hoursErr
+}
+
+func flushActivityHours(pending map[activityHourKey]int, now time.Time) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rows := make([]model.ActivityHourCounter, 0, len(pending))
+	for k, n := range pending {
+		rows = append(rows, model.ActivityHourCounter{Date: k.Date, Hour: k.Hour, Count: n})
+	}
+
+	err := adb.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "date"}, {Name: "hour"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":      gorm.Expr(`"count" + excluded."count"`),
+			"updated_at": now,
+		}),
+	}).CreateInBatches(&rows, 100).Error
+	if err != nil {
+		activityCounterLock.Lock()
+		for k, n := range pending {
+			pendingActivityHours[k] += n
+		}
+		activityCounterLock.Unlock()
+		return fmt.Errorf("failed to flush activity hours: %w", err)
+	}
+	return nil
+}
+
+type activityTotal struct {
+	GroupKey string
+	Kind     string
+	Total    int
+}
+
+// sumActivityCounters sums the counters from since to until, both inclusive, grouped by the column and kind,
+// filtered by the member if given.
+func sumActivityCounters(groupBy string, userID string, since, until string) []activityTotal {
+	query := adb.Model(&model.ActivityCounter{}).
+		Select(groupBy+" AS group_key, kind, SUM(count) AS total").
+		Where("date >= ? AND date <= ?", since, until)
+	if userID != "" {
+		query = query.Where("discord_user_id = ?", userID)
+	}
+
+	var totals []activityTotal
+	query.Group(groupBy + ", kind").Scan(&totals)
+	return totals
+}
+
+type activityStats map[string]int
+
+func (st activityStats) describe() string {
+	return fmt.Sprintf("메시지 %d / 수정 %d / 반응 %d / 입력 %d / 음성 %d분",
+		st[model.ActivityKindMessage], st[model.ActivityKindEdit], st[model.ActivityKindReaction],
+		st[model.ActivityKindTyping], st[model.ActivityKindVoice]/60)
+}
+
+// score orders channels and members, a voice minute weighs as much as a message.
+func (st activityStats) score() int {
+	return st[model.ActivityKindMessage] + st[model.ActivityKindReaction] + st[model.ActivityKindVoice]/60
+}
+
+func groupActivityTotals(totals []activityTotal) map[string]activityStats {
+	grouped := make(map[string]activityStats)
+	for _, t := range totals {
+		if grouped[t.GroupKey] == nil {
+			grouped[t.GroupKey] = make(activityStats)
+		}
+		grouped[t.GroupKey][t.Kind] += t.Total
+	}
+	return grouped
+}
+
+func sortedActivityKeys(grouped map[string]activityStats) []string {
+	var keys []string
+	for k := range grouped {
+		keys = append(keys, k)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if grouped[keys[i]].score() == grouped[keys[j]].score() {
+			return keys[i] < keys[j]
+		}
+		return grouped[keys[i]].score() > grouped[keys[j]].score()
+	})
+	return keys
+}
+
+func renderMemberActivity(member *discordgo.Member) string {
+	name := member.User.Username
+	if m, err := GetMainMemberInfo(member); err == nil {
+		name = m.Nickname
+	}
+	userID := member.User.ID
+	since := activityDate(time.Now().AddDate(0, 0, -activityReportDays+1))
+	today := activityDate(time.Now())
+
+	total := make(activityStats)
+	for _, t := range sumActivityCounters("discord_user_id", userID, since, today) {
+		total[t.Kind] += t.Total
+	}
+	msg := fmt.Sprintf("**[%s 님의 최근 %d일 활동]**\n* %s\n", name, activityReportDays, total.describe())
+
+	msg += fmt.Sprintf("\n**최근 %d일**\n", activityTrendDays)
+	byDate := groupActivityTotals(sumActivityCounters("date", userID, activityDate(time.Now().AddDate(0, 0, -activityTrendDays+1)), today))
+	for d := activityTrendDays - 1; d >= 0; d-- {
+		day := time.Now().AddDate(0, 0, -d)
+		st := byDate[activityDate(day)]
+		if st == nil {
+			st = make(activityStats)
+		}
+		msg += fmt.Sprintf("* %s (%s): %s\n", day.In(loc).Format("01-02"), weekdayNames[day.In(loc).Weekday()], st.describe())
+	}
+
+	byChannel := groupActivityTotals(sumActivityCounters("channel_id", userID, since, today))
+	if len(byChannel) > 0 {
+		msg += "\n**자주 활동한 채널**\n"
+		for idx, ch := range sortedActivityKeys(byChannel) {
+			if idx >= 5 {
+				break
+			}
+			msg += fmt.Sprintf("* <#%s>: %s\n", ch, byChannel[ch].describe())
+		}
+	}
+	return msg
+}
+
+func renderChannelActivity() string {
+	since := activityDate(time.Now().AddDate(0, 0, -activityReportDays+1))
+	today := activityDate(time.Now())
+	byChannel := groupActivityTotals(sumActivityCounters("channel_id", "", since, today))
+
+	msg := fmt.Sprintf("**[최근 %d일 채널별 활동]**\n", activityReportDays)
+	if len(byChannel) == 0 {
+		return msg + "기록된 활동이 없습니다.\n"
+	}
+
+	// active members per channel
+	type channelMembers struct {
+		ChannelID string
+		Members   int
+	}
+	var counts []channelMembers
+	adb.Model(&model.ActivityCounter{}).
+		Select("channel_id, COUNT(DISTINCT discord_user_id) AS members").
+		Where("date >= ?", since).Group("channel_id").Scan(&counts)
+	members := make(map[string]int)
+	for _, c := range counts {
+		members[c.ChannelID] = c.Members
+	}
+
+	for idx, ch := range sortedActivityKeys(byChannel) {
+		if idx >= activityTopChannels {
+			break
+		}
+		msg += fmt.Sprintf("%d. <#%s> (%d명): %s\n", idx+1, ch, members[ch], byChannel[ch].describe())
+	}
+	return msg
+}
+
+func RegisterActivityCommand(dg *discordgo.Session) error {
+	commands := []*discordgo.ApplicationCommand{
+		{
+			Name:        "활동",
+			Description: "길드원 및 채널별 활동 기록 명령어",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "길드원",
+					Description: "기록을 볼 길드원 (비우면 본인)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "음성",
+			Description: "음성 채널 이용 기록 및 주간 순위 명령어",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "길드원",
+					Description: "기록을 볼 길드원 (비우면 본인)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "활동순위공개",
+			Description: "주간 활동 리포트, 순위와 다른 길드원의 기록 조회에 내 활동 공개 여부 설정 명령어",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "공개",
+					Description: "리포트와 순위에 이름을 표시하고 다른 길드원이 기록을 볼 수 있게 할지 여부",
+					Required:    true,
+				},
+			},
+		},
+	}
+
+	for _, cmd := range commands {
+		_, err := dg.ApplicationCommandCreate(
+			dg.State.User.ID,
+			"",
+			cmd,
+		)
+		if err != nil {
+			fmt.Printf("Cannot create '%v' command: %v\n", cmd.Name, err)
+			return err
+		}
+
+		fmt.Printf("Registered command: /%s\n", cmd.Name)
+	}
+
+	return nil
+}
+
+func activityComponents(userID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "길드원 활동",
+					Style:    discordgo.PrimaryButton,
+					CustomID: "activity-member_" + userID,
+				},
+				discordgo.Button{
+					Label:    "채널별 활동",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "activity-channels_" + userID,
+				},
+			},
+		},
+	}
+}
+
+func activityCommandHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		data, ok := i.Data.(discordgo.ApplicationCommandInteractionData)
+		if !ok || data.Name != "활동" {
+			return
+		}
+
+		userID := interactionUserID(i)
+		for _, opt := range data.Options {
+			if opt.Name == "길드원" {
+				userID = opt.UserValue(nil).ID
+			}
+		}
+
+		member := cache.GetGuildMember(userID)
+		if member == nil {
+			respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+			return
+		}
+		if activityHiddenFrom(userID, interactionUserID(i)) {
+			respondEphemeral(s, i, "활동 기록을 공개하지 않은 길드원입니다.")
+			return
+		}
+
+		var flags discordgo.MessageFlags
+		if i.GuildID != "" {
+			flags = discordgo.MessageFlagsEphemeral
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         renderMemberActivity(member),
+				Components:      activityComponents(userID),
+				Flags:           flags,
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+	case discordgo.InteractionMessageComponent:
+		args := strings.Split(i.MessageComponentData().CustomID, "_")
+		if len(args) < 2 {
+			return
+		}
+
+		var msg string
+		switch args[0] {
+		case "activity-member":
+			member := cache.GetGuildMember(args[1])
+			if member == nil {
+				respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+				return
+			}
+			if activityHiddenFrom(args[1], interactionUserID(i)) {
+				respondEphemeral(s, i, "활동 기록을 공개하지 않은 길드원입니다.")
+				return
+			}
+			msg = renderMemberActivity(member)
+		case "activity-channels":
+			msg = renderChannelActivity()
+		default:
+			return
+		}
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:         msg,
+				Components:      activityComponents(args[1]),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useActivityDB points adb at a fresh database and starts with no pending counts.
+func useActivityDB(t *testing.T, migrate bool) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "activity.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrate {
+		if err := db.AutoMigrate(&model.ActivityCounter{}, &model.ActivityHourCounter{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prev := adb
+	adb = db
+	pendingActivityCounters = make(map[activityCounterKey]int)
+	pendingActivityHours = make(map[activityHourKey]int)
+	t.Cleanup(func() {
+		adb = prev
+		pendingActivityCounters = make(map[activityCounterKey]int)
+		pendingActivityHours = make(map[activityHourKey]int)
+	})
+	return db
+}
+
+func TestCountActivityBuckets(t *testing.T) {
+	useActivityDB(t, true)
+
+	countActivity("alice", "general", model.ActivityKindMessage)
+	countActivity("alice", "general", model.ActivityKindMessage)
+	countActivity("alice", "general", model.ActivityKindReaction)
+	countActivity("alice", "raid", model.ActivityKindMessage)
+	countActivity("bob", "general", model.ActivityKindMessage)
+
+	// one bucket per day, member, channel and kind
+	counts := make(map[activityCounterKey]int)
+	for k, n := range pendingActivityCounters {
+		k.Date = ""
+		counts[k] += n
+	}
+	want := map[activityCounterKey]int{
+		{UserID: "alice", ChannelID: "general", Kind: model.ActivityKindMessage}:  2,
+		{UserID: "alice", ChannelID: "general", Kind: model.ActivityKindReaction}: 1,
+		{UserID: "alice", ChannelID: "raid", Kind: model.ActivityKindMessage}:     1,
+		{UserID: "bob", ChannelID: "general", Kind: model.ActivityKindMessage}:    1,
+	}
+	if len(counts) != len(want) {
+		t.Errorf("got %d buckets, want %d: %v", len(counts), len(want), counts)
+	}
+	for k, n := range want {
+		if counts[k] != n {
+			t.Errorf("%+v: got %d, want %d", k, counts[k], n)
+		}
+	}
+
+	// the guild hour buckets count every event regardless of the member
+	hours := 0
+	for _, n := range pendingActivityHours {
+		hours += n
+	}
+	if hours != 5 {
+		t.Errorf("got %d events in the hour buckets, want 5", hours)
+	}
+}
+
+func TestFlushActivityCountersUpserts(t *testing.T) {
+	db := useActivityDB(t, true)
+
+	countActivity("alice", "general", model.ActivityKindMessage)
+	countActivity("alice", "general", model.ActivityKindMessage)
+	if err := flushActivityCounters(); err != nil {
+		t.Fatal(err)
+	}
+	if len(pendingActivityCounters) != 0 || len(pendingActivityHours) != 0 {
+		t.Fatalf("pending counts left after the flush: %v, %v", pendingActivityCounters, pendingActivityHours)
+	}
+
+	// the second flush adds to the same rows instead of inserting new ones
+	countActivity("alice", "general", model.ActivityKindMessage)
+	if err := flushActivityCounters(); err != nil {
+		t.Fatal(err)
+	}
+	totals := sumActivityCounters("channel_id", "alice", "0000-01-01", "9999-12-31")
+	if len(totals) != 1 || totals[0] != (activityTotal{GroupKey: "general", Kind: model.ActivityKindMessage, Total: 3}) {
+		t.Errorf("got totals %+v, want 3 messages in general", totals)
+	}
+
+	var rows, hourRows int64
+	db.Model(&model.ActivityCounter{}).Count(&rows)
+	db.Model(&model.ActivityHourCounter{}).Count(&hourRows)
+	var hours []model.ActivityHourCounter
+	db.Find(&hours)
+	total := 0
+	for _, h := range hours {
+		total += h.Count
+	}
+	// a flush straddling midnight or the hour may split the rows, but never the totals
+	if rows > 2 || hourRows > 2 || total != 3 {
+		t.Errorf("got %d counter rows and %d hour rows totalling %d, want the counts upserted into the same rows", rows, hourRows, total)
+	}
+}
+
+func TestFlushActivityCountersKeepsCountsOnFailure(t *testing.T) {
+	// without the tables every upsert fails
+	useActivityDB(t, false)
+
+	countActivity("alice", "general", model.ActivityKindTyping)
+	if err := flushActivityCounters(); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+
+	kept := 0
+	for k, n := range pendingActivityCounters {
+		if k.UserID == "alice" && k.Kind == model.ActivityKindTyping {
+			kept += n
+		}
+	}
+	hours := 0
+	for _, n := range pendingActivityHours {
+		hours += n
+	}
+	if kept != 1 || hours != 1 {
+		t.Errorf("got %d counts and %d hour counts kept, want 1 and 1", kept, hours)
+	}
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var bdb *gorm.DB
+
+func InfoBoardInit() error {
+	var err error
+	bdb, err = gorm.Open(sqlite.Open(environment.InfoBoardSQLiteDBPath), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	err = bdb.AutoMigrate(&model.InfoBoardMessage{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return nil
+}
+
+func InfoBoardFinalize() {
+	sqlDB, err := bdb.DB()
+	if err != nil {
+		fmt.Println("failed to get db connection for close: %w", err)
+		return
+	}
+	_ = sqlDB.Close()
+}
+
+// discord limits on embeds, counted in characters
+const (
+	embedDescriptionLimit = 4096
+	embedsPerMessageLimit = 10
+	embedsTotalLimit      = 6000
+)
+
+func embedLength(e *discordgo.MessageEmbed) int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	return n
+}
+
+// splitEmbedDescription splits the text into chunks within the limit, by line and then by word for long lines.
+func splitEmbedDescription(text string, limit int) []string {
+	var chunks []string
+	var sb strings.Builder
+	size := 0
+	flush := func() {
+		if chunk := strings.TrimRight(sb.String(), " \n"); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		sb.Reset()
+		size = 0
+	}
+	write := func(token string, sep string) {
+		n := utf8.RuneCountInString(token) + utf8.RuneCountInString(sep)
+		if size+n > limit {
+			flush()
+		}
+		// a chunk does not start with a bare line break
+		if size == 0 && token == "" {
+			return
+		}
+		sb.WriteString(token)
+		sb.WriteString(sep)
+		size += n
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if utf8.RuneCountInString(line)+1 <= limit {
+			write(line, "\n")
+			continue
+		}
+		for _, word := range strings.Fields(line) {
+			write(word, " ")
+		}
+		write("", "\n")
+	}
+	flush()
+	return chunks
+}
+
+// sectionEmbeds renders a board section, continuing into more embeds when the description is too long.
+func sectionEmbeds(title string, description string, color int) []*discordgo.MessageEmbed {
+	var embeds []*discordgo.MessageEmbed
+	for idx, chunk := range splitEmbedDescription(description, embedDescriptionLimit) {
+		t := title
+		if idx > 0 {
+			t += " (계속)"
+		}
+		embeds = append(embeds, &discordgo.MessageEmbed{
+			Title:       t,
+			Description: chunk,
+			Color:       color,
+		})
+	}
+	return embeds
+}
+
+// paginateEmbeds packs the embeds into messages within the per message limits.
+func paginateEmbeds(embeds []*discordgo.MessageEmbed) [][]*discordgo.MessageEmbed {
+	var pages [][]*discordgo.MessageEmbed
+	var page []*discordgo.MessageEmbed
+	size := 0
+	for _, e := range embeds {
+		n := embedLength(e)
+		if len(page) > 0 && (len(page) >= embedsPerMessageLimit || size+n > embedsTotalLimit) {
+			pages = append(pages, page)
+			page = nil
+			size = 0
+		}
+		page = append(page, e)
+		size += n
+	}
+	if len(page) > 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// legacyBoardMessageIDs returns the messages the boards used before their messages were stored, in display order.
+func legacyBoardMessageIDs() []string {
+	var ids []string
+	for _, id := range []string{
+		environment.DiscordGuildInfoByRoleMessageID,
+		environment.DiscordGuildInfoByRoleMessageID2,
+		environment.DiscordGuildInfoByLevelMessageID,
+		environment.DiscordGuildInfoByLevelMessageID2,
+	} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func isUnknownMessage(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMessage
+}
+
+// renderBoards shows the boards in the channel one after another, reusing the stored messages of the channel,
+// sending new ones when they grow and deleting the rest when they shrink.
+// A page is never shared by two boards.
+func renderBoards(s *discordgo.Session, channelID string, boards ...[]*discordgo.MessageEmbed) error {
+	var pages [][]*discordgo.MessageEmbed
+	for _, embeds := range boards {
+		pages = append(pages, paginateEmbeds(embeds)...)
+	}
+
+	var stored []model.InfoBoardMessage
+	bdb.Where("channel_id = ?", channelID).Order("position asc").Find(&stored)
+	if len(stored) == 0 {
+		for idx, id := range legacyBoardMessageIDs() {
+			m := model.InfoBoardMessage{ChannelID: channelID, Position: idx, MessageID: id}
+			bdb.Create(&m)
+			stored = append(stored, m)
+		}
+	}
+
+	empty := ""
+	for idx, page := range pages {
+		page := page
+		if idx >= len(stored) {
+			msg, err := s.ChannelMessageSendEmbeds(channelID, page)
+			if err != nil {
+				return fmt.Errorf("failed to send board message: %w", err)
+			}
+			bdb.Create(&model.InfoBoardMessage{ChannelID: channelID, Position: idx, MessageID: msg.ID})
+			continue
+		}
+
+		_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			Channel: channelID,
+			ID:      stored[idx].MessageID,
+			Content: &empty,
+			Embeds:  &page,
+		})
+		if err == nil {
+			continue
+		}
+		if !isUnknownMessage(err) {
+			return fmt.Errorf("failed to edit board message %s: %w", stored[idx].MessageID, err)
+		}
+
+		// the message is gone, a new one would come after the rest of the boards,
+		// so send the pages from here on again in order
+		fmt.Printf("board message %s is gone, sending the boards again from page %d\n", stored[idx].MessageID, idx)
+		deleteBoardMessages(s, channelID, stored[idx:])
+		stored = stored[:idx]
+		msg, err := s.ChannelMessageSendEmbeds(channelID, page)
+		if err != nil {
+			return fmt.Errorf("failed to send board message: %w", err)
+		}
+		bdb.Create(&model.InfoBoardMessage{ChannelID: channelID, Position: idx, MessageID: msg.ID})
+	}
+
+	// delete messages no longer needed
+	if len(stored) > len(pages) {
+		deleteBoardMessages(s, channelID, stored[len(pages):])
+	}
+	return nil
+}
+
+func deleteBoardMessages(s *discordgo.Session, channelID string, messages []model.InfoBoardMessage) {
+	for _, m := range messages {
+		if err := s.ChannelMessageDelete(channelID, m.MessageID); err != nil && !isUnknownMessage(err) {
+			fmt.Printf("failed to delete board message %s: %v\n", m.MessageID, err)
+		}
+		bdb.Unscoped().Delete(&m)
+	}
+}
+
+func boardHeaderEmbed(title string, description string, color int) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		Description: description,
+		Color:       color,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSplitEmbedDescription(t *testing.T) {
+	if got := splitEmbedDescription("", 10); len(got) != 0 {
+		t.Errorf("empty text: got %q", got)
+	}
+	if got, want := splitEmbedDescription("aaaa\nbbbb\ncccc", 10), []string{"aaaa\nbbbb", "cccc"}; !slices.Equal(got, want) {
+		t.Errorf("by line: got %q, want %q", got, want)
+	}
+	// a line longer than the limit is split by word and the next line starts a new chunk
+	if got, want := splitEmbedDescription("x\naaa bbb ccc\ny", 8), []string{"x\naaa", "bbb ccc", "y"}; !slices.Equal(got, want) {
+		t.Errorf("long line: got %q, want %q", got, want)
+	}
+	// the limit is in characters, not bytes
+	if got, want := splitEmbedDescription("가나다라\n마바사아", 10), []string{"가나다라\n마바사아"}; !slices.Equal(got, want) {
+		t.Errorf("multi-byte: got %q, want %q", got, want)
+	}
+}
+
+func TestSectionEmbedsKeepEveryLine(t *testing.T) {
+	var lines []string
+	for idx := 0; idx < 500; idx++ {
+		lines = append(lines, fmt.Sprintf("* 길드원%03d Lv.%d 비숍", idx, 100+idx%100))
+	}
+	embeds := sectionEmbeds("길드원 목록", strings.Join(lines, "\n"), 0)
+	if len(embeds) < 2 {
+		t.Fatalf("got %d embeds, want the list to continue", len(embeds))
+	}
+
+	var got []string
+	for idx, e := range embeds {
+		if n := utf8.RuneCountInString(e.Description); n > embedDescriptionLimit {
+			t.Errorf("embed %d description is %d characters long", idx, n)
+		}
+		if want := "길드원 목록 (계속)"; idx > 0 && e.Title != want {
+			t.Errorf("embed %d title %q, want %q", idx, e.Title, want)
+		}
+		got = append(got, strings.Split(e.Description, "\n")...)
+	}
+	if !slices.Equal(got, lines) {
+		t.Errorf("lines were lost or reordered across the embeds")
+	}
+}
+
+func TestPaginateEmbeds(t *testing.T) {
+	embeds := func(n, size int) []*discordgo.MessageEmbed {
+		var es []*discordgo.MessageEmbed
+		for idx := 0; idx < n; idx++ {
+			es = append(es, &discordgo.MessageEmbed{Description: strings.Repeat("a", size)})
+		}
+		return es
+	}
+	pageSizes := func(pages [][]*discordgo.MessageEmbed) []int {
+		var sizes []int
+		for _, page := range pages {
+			sizes = append(sizes, len(page))
+		}
+		return sizes
+	}
+
+	if pages := paginateEmbeds(nil); len(pages) != 0 {
+		t.Errorf("no embeds: got %d pages", len(pages))
+	}
+	if got := pageSizes(paginateEmbeds(embeds(11, 10))); !slices.Equal(got, []int{10, 1}) {
+		t.Errorf("embeds per message: got pages of %v", got)
+	}
+	if got := pageSizes(paginateEmbeds(embeds(3, embedDescriptionLimit))); !slices.Equal(got, []int{1, 1, 1}) {
+		t.Errorf("full descriptions: got pages of %v", got)
+	}
+	// 3 × 2000 is exactly the total limit
+	if got := pageSizes(paginateEmbeds(embeds(4, 2000))); !slices.Equal(got, []int{3, 1}) {
+		t.Errorf("up to the total: got pages of %v", got)
+	}
+}
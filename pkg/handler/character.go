@@ -0,0 +1,563 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/discord"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var cdb *gorm.DB
+
+// jobAliases are the short job names members usually type.
+var jobAliases = map[string]string{
+	"썬콜": "아크메이지(썬,콜)",
+	"불독": "아크메이지(불,독)",
+	"다크": "다크나이트",
+	"보마": "보우마스터",
+	"나로": "나이트로드",
+}
+
+func CharacterInit(dg *discordgo.Session) error {
+	var err error
+	cdb, err = gorm.Open(sqlite.Open(environment.CharacterSQLiteDBPath), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	err = cdb.AutoMigrate(&model.Character{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	if err := RegisterCharacterCommand(dg); err != nil {
+		return err
+	}
+
+	// characters registered before approvals existed, or whose request could not be posted
+	var pending []model.Character
+	cdb.Where("approved = ? AND approval_message_id = ?", false, "").Find(&pending)
+	for _, c := range pending {
+		requestCharacterApproval(dg, c)
+	}
+
+	dg.AddHandler(characterHandler)
+	return nil
+}
+
+func CharacterFinalize() {
+	sqlDB, err := cdb.DB()
+	if err != nil {
+		fmt.Println("failed to get db connection for close: %w", err)
+		return
+	}
+	_ = sqlDB.Close()
+}
+
+func RegisterCharacterCommand(dg *discordgo.Session) error {
+	commands := []*discordgo.ApplicationCommand{
+		{
+			Name:        "캐릭터",
+			Description: "캐릭터 등록 및 대표 캐릭터 설정 명령어",
+		},
+	}
+
+	for _, cmd := range commands {
+		_, err := dg.ApplicationCommandCreate(
+			dg.State.User.ID,
+			"",
+			cmd,
+		)
+		if err != nil {
+			fmt.Printf("Cannot create '%v' command: %v\n", cmd.Name, err)
+			return err
+		}
+
+		fmt.Printf("Registered command: /%s\n", cmd.Name)
+	}
+
+	return nil
+}
+
+// listCharacters returns the registered characters of the user, main character first.
+func listCharacters(userID string) []model.Character {
+	var characters []model.Character
+	cdb.Where("discord_user_id = ?", userID).Order("main desc, level desc, id asc").Find(&characters)
+	return characters
+}
+
+// listApprovedCharacters returns the characters of the user that can be used in place of the Discord nickname.
+func listApprovedCharacters(userID string) []model.Character {
+	var characters []model.Character
+	cdb.Where("discord_user_id = ? AND approved = ?", userID, true).Order("main desc, level desc, id asc").Find(&characters)
+	return characters
+}
+
+func characterMemberInfo(c model.Character) model.MemberInfo {
+	return model.MemberInfo{
+		MainRoleName: c.MainRoleName,
+		SubRoleName:  c.SubRoleName,
+		Level:        c.Level,
+		Nickname:     c.Nickname,
+		Mention:      fmt.Sprintf("<@%s>", c.DiscordUserID),
+	}
+}
+
+func describeCharacter(c model.Character) string {
+	return fmt.Sprintf("%s / %d / %s", c.SubRoleName, c.Level, c.Nickname)
+}
+
+// matchesDiscordNickname reports whether the character is the one in the Discord nickname of the user,
+// which needs no officer approval.
+func matchesDiscordNickname(c model.Character) bool {
+	member := cache.GetGuildMember(c.DiscordUserID)
+	if member == nil {
+		return false
+	}
+	m, err := GetMemberInfoFromMember(member)
+	if err != nil {
+		return false
+	}
+	return m.Nickname == c.Nickname && m.SubRoleName == c.SubRoleName && m.Level == c.Level
+}
+
+// GetMainMemberInfo returns the member info of the main character if the member registered an approved one,
+// otherwise the one parsed from the Discord nickname.
+func GetMainMemberInfo(member *discordgo.Member) (*model.MemberInfo, error) {
+	var c model.Character
+	result := cdb.Where("discord_user_id = ? AND main = ? AND approved = ?", member.User.ID, true, true).Limit(1).Find(&c)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get main character: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		m := characterMemberInfo(c)
+		return &m, nil
+	}
+	return GetMemberInfoFromMember(member)
+}
+
+// getCharacterMemberInfo returns the member info of the chosen character of the member.
+// characterID "0" chooses the Discord nickname.
+func getCharacterMemberInfo(member *discordgo.Member, characterID string) (*model.MemberInfo, error) {
+	if characterID == "" || characterID == "0" {
+		return GetMemberInfoFromMember(member)
+	}
+
+	var c model.Character
+	err := cdb.Where("id = ? AND discord_user_id = ? AND approved = ?", characterID, member.User.ID, true).First(&c).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get character %s: %w", characterID, err)
+	}
+	m := characterMemberInfo(c)
+	return &m, nil
+}
+
+// findMemberInfoByNickname looks up an approved character first, then the Discord nickname of guild members.
+func findMemberInfoByNickname(nickname string) (*model.MemberInfo, error) {
+	var c model.Character
+	if cdb.Where("nickname = ? AND approved = ?", nickname, true).Limit(1).Find(&c).RowsAffected > 0 {
+		m := characterMemberInfo(c)
+		return &m, nil
+	}
+
+	member, ok := cache.ListAllMembersNicknameMap()[nickname]
+	if !ok {
+		return nil, fmt.Errorf("cannot find member: %s", nickname)
+	}
+	return GetMemberInfoFromMember(member)
+}
+
+func resolveJob(job string) (string, string, bool) {
+	job = strings.ReplaceAll(strings.TrimSpace(job), " ", "")
+	if alias, ok := jobAliases[job]; ok {
+		job = alias
+	}
+	for mainRole, subRoles := range mainRoleList {
+		if slices.Contains(subRoles, job) {
+			return mainRole, job, true
+		}
+	}
+	return "", "", false
+}
+
+func characterHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		data, ok := i.Data.(discordgo.ApplicationCommandInteractionData)
+		if !ok || data.Name != "캐릭터" {
+			return
+		}
+		if cache.GetGuildMember(interactionUserID(i)) == nil {
+			respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+			return
+		}
+		characterLandingHandler(s, i, false, "")
+	case discordgo.InteractionMessageComponent:
+		data := i.MessageComponentData()
+		if args := strings.Split(data.CustomID, "_"); len(args) == 2 {
+			switch args[0] {
+			case "character-approve":
+				characterApprovalHandler(s, i, args[1], true)
+			case "character-reject":
+				characterApprovalHandler(s, i, args[1], false)
+			}
+			return
+		}
+		switch data.CustomID {
+		case "character-landing":
+			characterLandingHandler(s, i, true, "")
+		case "character-add":
+			discord.SendCharacterModal(s, i.Interaction)
+		case "character-main":
+			characterSelectHandler(s, i, "character-main-select", "대표 캐릭터로 설정할 캐릭터를 선택하세요.")
+		case "character-remove":
+			characterSelectHandler(s, i, "character-remove-select", "삭제할 캐릭터를 선택하세요.")
+		case "character-main-select":
+			characterSetMainHandler(s, i, data.Values[0])
+		case "character-remove-select":
+			characterRemoveHandler(s, i, data.Values[0])
+		}
+	case discordgo.InteractionModalSubmit:
+		if i.ModalSubmitData().CustomID == "character-modal" {
+			characterModalHandler(s, i)
+		}
+	}
+}
+
+func characterLandingHandler(s *discordgo.Session, i *discordgo.InteractionCreate, update bool, notice string) {
+	characters := listCharacters(interactionUserID(i))
+
+	var msg string
+	if notice != "" {
+		msg += notice + "\n\n"
+	}
+	msg += "**[내 캐릭터]**\n"
+	if len(characters) == 0 {
+		msg += "등록된 캐릭터가 없습니다. 디스코드 닉네임의 캐릭터로 레이드와 투표에 참여합니다.\n"
+	}
+	for _, c := range characters {
+		line := "* " + describeCharacter(c)
+		if c.Main {
+			line += " (대표)"
+		}
+		if !c.Approved {
+			line += " (승인 대기)"
+		}
+		msg += line + "\n"
+	}
+	msg += "\n대표 캐릭터는 투표와 직업/레벨 분포에 사용되며, 레이드 신청 시에는 참가할 캐릭터를 고를 수 있습니다."
+	msg += "\n디스코드 닉네임과 다른 캐릭터는 운영진 승인 후에 사용되며, 그 전까지는 디스코드 닉네임의 캐릭터로 참여합니다."
+
+	responseType := discordgo.InteractionResponseChannelMessageWithSource
+	if update {
+		responseType = discordgo.InteractionResponseUpdateMessage
+	}
+	var flags discordgo.MessageFlags
+	if i.GuildID != "" {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: responseType,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   flags,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "캐릭터 등록/수정",
+							Style:    discordgo.PrimaryButton,
+							CustomID: "character-add",
+						},
+						discordgo.Button{
+							Label:    "대표 캐릭터 설정",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "character-main",
+							Disabled: len(characters) == 0,
+						},
+						discordgo.Button{
+							Label:    "캐릭터 삭제",
+							Style:    discordgo.DangerButton,
+							CustomID: "character-remove",
+							Disabled: len(characters) == 0,
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func characterSelectHandler(s *discordgo.Session, i *discordgo.InteractionCreate, customID string, msg string) {
+	var options []discordgo.SelectMenuOption
+	for _, c := range listCharacters(interactionUserID(i)) {
+		if len(options) >= 25 {
+			break
+		}
+		options = append(options, discordgo.SelectMenuOption{
+			Label: describeCharacter(c),
+			Value: strconv.Itoa(int(c.ID)),
+		})
+	}
+	if len(options) == 0 {
+		characterLandingHandler(s, i, true, "등록된 캐릭터가 없습니다.")
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.SelectMenu{
+							CustomID:    customID,
+							Placeholder: "캐릭터 선택",
+							Options:     options,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "돌아가기",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "character-landing",
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func characterSetMainHandler(s *discordgo.Session, i *discordgo.InteractionCreate, characterID string) {
+	userID := interactionUserID(i)
+
+	var c model.Character
+	err := cdb.Where("id = ? AND discord_user_id = ?", characterID, userID).First(&c).Error
+	if err != nil {
+		characterLandingHandler(s, i, true, "캐릭터를 찾을 수 없습니다.")
+		return
+	}
+
+	cdb.Model(&model.Character{}).Where("discord_user_id = ?", userID).Update("main", false)
+	cdb.Model(&c).Update("main", true)
+
+	characterLandingHandler(s, i, true, fmt.Sprintf("%s 을(를) 대표 캐릭터로 설정했습니다.", c.Nickname))
+}
+
+func characterRemoveHandler(s *discordgo.Session, i *discordgo.InteractionCreate, characterID string) {
+	userID := interactionUserID(i)
+
+	var c model.Character
+	err := cdb.Where("id = ? AND discord_user_id = ?", characterID, userID).First(&c).Error
+	if err != nil {
+		characterLandingHandler(s, i, true, "캐릭터를 찾을 수 없습니다.")
+		return
+	}
+
+	deleteCharacter(s, c)
+
+	characterLandingHandler(s, i, true, fmt.Sprintf("%s 캐릭터를 삭제했습니다.", c.Nickname))
+}
+
+func characterModalHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var nickname, job, level string
+	for _, comp := range i.ModalSubmitData().Components {
+		if ar, ok := comp.(*discordgo.ActionsRow); ok {
+			if ti, ok := ar.Components[0].(*discordgo.TextInput); ok {
+				switch ti.CustomID {
+				case "nickname":
+					nickname = strings.TrimSpace(ti.Value)
+				case "job":
+					job = ti.Value
+				case "level":
+					level = strings.TrimSpace(ti.Value)
+				}
+			}
+		}
+	}
+
+	if nickname == "" || strings.ContainsAny(nickname, " _") {
+		characterLandingHandler(s, i, true, "닉네임에는 공백이나 '_'를 사용할 수 없습니다.")
+		return
+	}
+	mainRole, subRole, ok := resolveJob(job)
+	if !ok {
+		var jobs []string
+		for _, subRoles := range mainRoleList {
+			jobs = append(jobs, subRoles...)
+		}
+		slices.Sort(jobs)
+		characterLandingHandler(s, i, true, fmt.Sprintf("직업 '%s'을(를) 찾을 수 없습니다. (%s)", job, strings.Join(jobs, ", ")))
+		return
+	}
+	lv, err := strconv.Atoi(level)
+	if err != nil || lv < 1 || lv > 200 {
+		characterLandingHandler(s, i, true, fmt.Sprintf("레벨 '%s'이(가) 올바르지 않습니다.", level))
+		return
+	}
+
+	userID := interactionUserID(i)
+
+	var c model.Character
+	err = cdb.Where("nickname = ?", nickname).First(&c).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		characterLandingHandler(s, i, true, "캐릭터 등록 중 오류가 발생했습니다.")
+		return
+	}
+	if err == nil && c.DiscordUserID != userID && !c.Approved &&
+		matchesDiscordNickname(model.Character{DiscordUserID: userID, Nickname: nickname, SubRoleName: subRole, Level: lv}) {
+		// an unapproved registration gives way to the member whose Discord nickname is the character
+		deleteCharacter(s, c)
+		err = gorm.ErrRecordNotFound
+	}
+	if err == nil && c.DiscordUserID != userID {
+		characterLandingHandler(s, i, true, fmt.Sprintf("%s 은(는) 다른 길드원이 등록한 캐릭터입니다.", nickname))
+		return
+	}
+
+	notice := fmt.Sprintf("%s 캐릭터를 수정했습니다.", nickname)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// the first character becomes the main one
+		c = model.Character{
+			DiscordUserID: userID,
+			Nickname:      nickname,
+			Main:          len(listCharacters(userID)) == 0,
+		}
+		notice = fmt.Sprintf("%s 캐릭터를 등록했습니다.", nickname)
+	}
+	changed := c.MainRoleName != mainRole || c.SubRoleName != subRole || c.Level != lv
+	c.MainRoleName = mainRole
+	c.SubRoleName = subRole
+	c.Level = lv
+	if changed {
+		c.Approved = matchesDiscordNickname(c)
+	}
+	cdb.Save(&c)
+
+	if !c.Approved {
+		requestCharacterApproval(s, c)
+		notice += " 운영진 승인 후에 사용됩니다."
+	}
+	characterLandingHandler(s, i, true, notice)
+}
+
+// deleteCharacter removes the character along with its open approval request, keeping a main character while any is left.
+func deleteCharacter(s *discordgo.Session, c model.Character) {
+	closeCharacterApprovalRequest(s, c, "→ 캐릭터가 삭제되었습니다.")
+
+	// hard delete so the nickname can be registered again
+	cdb.Unscoped().Delete(&c)
+
+	if c.Main {
+		if rest := listCharacters(c.DiscordUserID); len(rest) > 0 {
+			cdb.Model(&rest[0]).Update("main", true)
+		}
+	}
+}
+
+// requestCharacterApproval posts the approval request of the character to the officers, replacing an earlier one.
+func requestCharacterApproval(s *discordgo.Session, c model.Character) {
+	closeCharacterApprovalRequest(s, c, "→ 새 요청으로 대체되었습니다.")
+
+	discordInfo := "디스코드 닉네임 확인 불가"
+	if member := cache.GetGuildMember(c.DiscordUserID); member != nil {
+		if m, err := GetMemberInfoFromMember(member); err == nil {
+			discordInfo = fmt.Sprintf("디스코드 닉네임: %s / %d / %s", m.SubRoleName, m.Level, m.Nickname)
+		}
+	}
+
+	msg, err := s.ChannelMessageSendComplex(environment.DiscordGuildPollChannelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("**[캐릭터 승인 요청]** <@%s> %s\n(%s)", c.DiscordUserID, describeCharacter(c), discordInfo),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "승인",
+						Style:    discordgo.PrimaryButton,
+						CustomID: fmt.Sprintf("character-approve_%d", c.ID),
+					},
+					discordgo.Button{
+						Label:    "거절",
+						Style:    discordgo.DangerButton,
+						CustomID: fmt.Sprintf("character-reject_%d", c.ID),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		fmt.Println("failed to send character approval request:", err)
+		return
+	}
+	cdb.Model(&c).Update("approval_message_id", msg.ID)
+}
+
+// closeCharacterApprovalRequest removes the buttons of the open approval request of the character with the note.
+func closeCharacterApprovalRequest(s *discordgo.Session, c model.Character, note string) {
+	if c.ApprovalMessageID == "" {
+		return
+	}
+	msg, err := s.ChannelMessage(environment.DiscordGuildPollChannelID, c.ApprovalMessageID)
+	if err != nil {
+		return
+	}
+	content := msg.Content + "\n" + note
+	components := []discordgo.MessageComponent{}
+	_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:         environment.DiscordGuildPollChannelID,
+		ID:              c.ApprovalMessageID,
+		Content:         &content,
+		Components:      &components,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		fmt.Println("failed to close character approval request:", err)
+	}
+}
+
+func characterApprovalHandler(s *discordgo.Session, i *discordgo.InteractionCreate, characterID string, approve bool) {
+	if !isRaidAdmin(i) {
+		respondEphemeral(s, i, "운영진만 캐릭터를 승인할 수 있습니다.")
+		return
+	}
+
+	var c model.Character
+	if err := cdb.First(&c, characterID).Error; err != nil {
+		respondEphemeral(s, i, "캐릭터를 찾을 수 없습니다. 이미 삭제되었거나 수정되었습니다.")
+		return
+	}
+
+	result := "승인"
+	if approve {
+		cdb.Model(&c).Updates(map[string]interface{}{"approved": true, "approval_message_id": ""})
+		sendMessage(s, c.DiscordUserID, fmt.Sprintf("%s 캐릭터가 승인되었습니다.", describeCharacter(c)))
+	} else {
+		result = "거절"
+		c.ApprovalMessageID = ""
+		deleteCharacter(s, c)
+		sendMessage(s, c.DiscordUserID, fmt.Sprintf("%s 캐릭터 등록이 거절되었습니다. 운영진에게 문의해주세요.", describeCharacter(c)))
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         i.Message.Content + fmt.Sprintf("\n→ <@%s> 님이 %s했습니다.", interactionUserID(i), result),
+			Components:      []discordgo.MessageComponent{},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// guildCompositionTrendWeeks is how many weekly points the trend shows.
+const guildCompositionTrendWeeks = 8
+
+var guildCompositionPeriods = []int{1, 4, 12}
+
+var subRoleOrder = []string{"히어로", "팔라딘", "다크나이트", "보우마스터", "신궁", "아크메이지(썬,콜)", "아크메이지(불,독)", "비숍", "나이트로드", "섀도어"}
+
+// listGuildMemberInfos returns the main character of every member, skipping members whose info cannot be parsed.
+func listGuildMemberInfos() []model.MemberInfo {
+	var ms []model.MemberInfo
+	for _, member := range cache.ListAllMembers() {
+		m, err := GetMainMemberInfo(member)
+		if err != nil {
+			continue
+		}
+		ms = append(ms, *m)
+	}
+	return ms
+}
+
+func buildGuildSnapshot(date string, ms []model.MemberInfo) model.GuildSnapshot {
+	snapshot := model.GuildSnapshot{Date: date, MemberCount: len(ms)}
+	if len(ms) == 0 {
+		return snapshot
+	}
+
+	levels := make([]int, 0, len(ms))
+	jobLevels := make(map[string][]int)
+	bands := make(map[int]int)
+	for _, m := range ms {
+		levels = append(levels, m.Level)
+		jobLevels[m.SubRoleName] = append(jobLevels[m.SubRoleName], m.Level)
+		bands[m.Level/10*10]++
+	}
+
+	sort.Ints(levels)
+	snapshot.AverageLevel = averageLevel(levels)
+	if len(levels)%2 == 0 {
+		snapshot.MedianLevel = (levels[len(levels)/2-1] + levels[len(levels)/2]) / 2
+	} else {
+		snapshot.MedianLevel = levels[len(levels)/2]
+	}
+
+	for job, lvs := range jobLevels {
+		snapshot.Jobs = append(snapshot.Jobs, model.GuildJobSnapshot{
+			SubRoleName:  job,
+			Count:        len(lvs),
+			AverageLevel: averageLevel(lvs),
+		})
+	}
+	for band, count := range bands {
+		snapshot.LevelBands = append(snapshot.LevelBands, model.GuildLevelBandSnapshot{Band: band, Count: count})
+	}
+	return snapshot
+}
+
+func averageLevel(levels []int) float64 {
+	if len(levels) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, lv := range levels {
+		sum += lv
+	}
+	return float64(sum) / float64(len(levels))
+}
+
+// GuildCompositionSnapshotRefresh records the composition of the guild once a day.
+func GuildCompositionSnapshotRefresh() error {
+	date := time.Now().In(loc).Format("2006-01-02")
+
+	var count int64
+	ldb.Model(&model.GuildSnapshot{}).Where("date = ?", date).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	ms := listGuildMemberInfos()
+	if len(ms) == 0 {
+		// the member cache is not ready yet
+		return nil
+	}
+
+	snapshot := buildGuildSnapshot(date, ms)
+	if err := ldb.Create(&snapshot).Error; err != nil {
+		return fmt.Errorf("failed to save guild snapshot: %w", err)
+	}
+	return nil
+}
+
+// findGuildSnapshot returns the latest snapshot at or before the date, or the oldest one when there is none.
+func findGuildSnapshot(date string) (model.GuildSnapshot, error) {
+	var snapshot model.GuildSnapshot
+	err := ldb.Preload("Jobs").Preload("LevelBands").Where("date <= ?", date).Order("date desc").First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ldb.Preload("Jobs").Preload("LevelBands").Order("date asc").First(&snapshot).Error
+	}
+	return snapshot, err
+}
+
+func formatCountDiff(diff int) string {
+	if diff > 0 {
+		return fmt.Sprintf("+%d", diff)
+	}
+	return strconv.Itoa(diff)
+}
+
+func formatLevelDiff(diff float64) string {
+	if diff >= 0 {
+		return fmt.Sprintf("+%.1f", diff)
+	}
+	return fmt.Sprintf("%.1f", diff)
+}
+
+// renderGuildComposition compares the current composition with the one weeks ago, and shows the weekly trend.
+func renderGuildComposition(weeks int) string {
+	now := time.Now().In(loc)
+	current := buildGuildSnapshot(now.Format("2006-01-02"), listGuildMemberInfos())
+
+	past, err := findGuildSnapshot(now.AddDate(0, 0, -7*weeks).Format("2006-01-02"))
+	if err != nil {
+		return "아직 기록된 길드 구성이 없습니다. 길드 구성은 하루에 한 번 기록됩니다.\n"
+	}
+
+	msg := fmt.Sprintf("**[길드 구성 변화]** %s → %s (%d주)\n", past.Date, current.Date, weeks)
+	msg += fmt.Sprintf("* 인원: %d명 → %d명 (%s)\n", past.MemberCount, current.MemberCount, formatCountDiff(current.MemberCount-past.MemberCount))
+	msg += fmt.Sprintf("* 평균 레벨: %.1f → %.1f (%s)\n", past.AverageLevel, current.AverageLevel, formatLevelDiff(current.AverageLevel-past.AverageLevel))
+	msg += fmt.Sprintf("* 중앙값 레벨: %d → %d (%s)\n", past.MedianLevel, current.MedianLevel, formatCountDiff(current.MedianLevel-past.MedianLevel))
+
+	// jobs
+	pastJobs := make(map[string]model.GuildJobSnapshot)
+	for _, j := range past.Jobs {
+		pastJobs[j.SubRoleName] = j
+	}
+	currentJobs := make(map[string]model.GuildJobSnapshot)
+	for _, j := range current.Jobs {
+		currentJobs[j.SubRoleName] = j
+	}
+	var summary []string
+	msg += "\n**직업별**\n"
+	for _, job := range subRoleOrder {
+		p, c := pastJobs[job], currentJobs[job]
+		if p.Count == 0 && c.Count == 0 {
+			continue
+		}
+		msg += fmt.Sprintf("* %s: %d명 → %d명 (%s) / 평균 %.1f (%s)\n", job, p.Count, c.Count, formatCountDiff(c.Count-p.Count),
+			c.AverageLevel, formatLevelDiff(c.AverageLevel-p.AverageLevel))
+		if c.Count != p.Count {
+			summary = append(summary, fmt.Sprintf("%s %s", job, formatCountDiff(c.Count-p.Count)))
+		}
+	}
+
+	// level bands
+	pastBands := make(map[int]int)
+	for _, b := range past.LevelBands {
+		pastBands[b.Band] = b.Count
+	}
+	currentBands := make(map[int]int)
+	for _, b := range current.LevelBands {
+		currentBands[b.Band] = b.Count
+	}
+	msg += "\n**레벨 구간**\n"
+	for band := 200; band > 0; band -= 10 {
+		p, c := pastBands[band], currentBands[band]
+		if p == 0 && c == 0 {
+			continue
+		}
+		msg += fmt.Sprintf("* %d ~ %d: %d명 → %d명 (%s)\n", band, band+9, p, c, formatCountDiff(c-p))
+	}
+
+	// weekly trend
+	var trend []string
+	lastDate := ""
+	for w := guildCompositionTrendWeeks; w >= 1; w-- {
+		sn, err := findGuildSnapshot(now.AddDate(0, 0, -7*w).Format("2006-01-02"))
+		if err != nil || sn.Date == lastDate {
+			continue
+		}
+		lastDate = sn.Date
+		trend = append(trend, fmt.Sprintf("* %s: %d명 / 평균 %.1f", sn.Date[5:], sn.MemberCount, sn.AverageLevel))
+	}
+	trend = append(trend, fmt.Sprintf("* %s: %d명 / 평균 %.1f (현재)", current.Date[5:], current.MemberCount, current.AverageLevel))
+	msg += "\n**주간 추이**\n" + strings.Join(trend, "\n") + "\n"
+
+	summary = append(summary, fmt.Sprintf("평균 레벨 %s", formatLevelDiff(current.AverageLevel-past.AverageLevel)))
+	msg += fmt.Sprintf("\n요약: %s (%s 이후)\n", strings.Join(summary, ", "), past.Date)
+	return msg
+}
+
+func guildCompositionComponents(weeks int) []discordgo.MessageComponent {
+	var buttons []discordgo.MessageComponent
+	for _, w := range guildCompositionPeriods {
+		style := discordgo.SecondaryButton
+		if w == weeks {
+			style = discordgo.PrimaryButton
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    fmt.Sprintf("%d주 전과 비교", w),
+			Style:    style,
+			CustomID: fmt.Sprintf("guild-composition_%d", w),
+		})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// GuildCompositionInit migrates the composition snapshots into the level tracker database, so it must run after LevelTrackerInit.
+func GuildCompositionInit(dg *discordgo.Session) error {
+	err := ldb.AutoMigrate(&model.GuildSnapshot{}, &model.GuildJobSnapshot{}, &model.GuildLevelBandSnapshot{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	if err := RegisterGuildCompositionCommand(dg); err != nil {
+		return err
+	}
+
+	dg.AddHandler(guildCompositionHandler)
+	return nil
+}
+
+func RegisterGuildCompositionCommand(dg *discordgo.Session) error {
+	cmd := &discordgo.ApplicationCommand{
+		Name:        "길드현황",
+		Description: "길드 직업/레벨 구성 변화 리포트 명령어",
+	}
+
+	_, err := dg.ApplicationCommandCreate(
+		dg.State.User.ID,
+		"",
+		cmd,
+	)
+	if err != nil {
+		fmt.Printf("Cannot create '%v' command: %v\n", cmd.Name, err)
+		return err
+	}
+
+	fmt.Printf("Registered command: /%s\n", cmd.Name)
+	return nil
+}
+
+func guildCompositionHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		data, ok := i.Data.(discordgo.ApplicationCommandInteractionData)
+		if !ok || data.Name != "길드현황" {
+			return
+		}
+
+		var flags discordgo.MessageFlags
+		if i.GuildID != "" {
+			flags = discordgo.MessageFlagsEphemeral
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:    renderGuildComposition(4),
+				Components: guildCompositionComponents(4),
+				Flags:      flags,
+			},
+		})
+	case discordgo.InteractionMessageComponent:
+		args := strings.Split(i.MessageComponentData().CustomID, "_")
+		if args[0] != "guild-composition" || len(args) < 2 {
+			return
+		}
+		weeks, err := strconv.Atoi(args[1])
+		if err != nil {
+			return
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    renderGuildComposition(weeks),
+				Components: guildCompositionComponents(weeks),
+			},
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var inactivityPeriods = []int{7, 14, 30}
+
+// inactivityReportLimit keeps the report within the 2000 characters of an interaction response.
+const inactivityReportLimit = 1800
+
+type inactiveMember struct {
+	UserID       string
+	Info         model.MemberInfo
+	LastActivity time.Time
+}
+
+// lastActivityByMention returns the last persisted activity time of every member, keyed by mention.
+func lastActivityByMention() map[string]time.Time {
+	var persisted []MemberInfoPersist
+	adb.Find(&persisted)
+
+	last := make(map[string]time.Time)
+	for _, p := range persisted {
+		if p.LastActivityTime.After(last[p.Mention]) {
+			last[p.Mention] = p.LastActivityTime
+		}
+	}
+	return last
+}
+
+// listInactiveMembers returns the members without activity for the days, least recently active first.
+// Members without any recorded activity come first with a zero LastActivity.
+func listInactiveMembers(days int) []inactiveMember {
+	last := lastActivityByMention()
+	threshold := time.Now().AddDate(0, 0, -days)
+
+	var inactive []inactiveMember
+	for _, member := range cache.ListAllMembers() {
+		m, err := GetMainMemberInfo(member)
+		if err != nil {
+			continue
+		}
+		t := last[m.Mention]
+		if t.After(threshold) {
+			continue
+		}
+		inactive = append(inactive, inactiveMember{UserID: member.User.ID, Info: *m, LastActivity: t})
+	}
+
+	sort.SliceStable(inactive, func(i, j int) bool {
+		return inactive[i].LastActivity.Before(inactive[j].LastActivity)
+	})
+	return inactive
+}
+
+func describeLastActivity(t time.Time) string {
+	if t.IsZero() {
+		return "활동 기록 없음"
+	}
+	return fmt.Sprintf("마지막 활동 %s (%d일 전)", t.In(loc).Format("2006-01-02"), int(time.Since(t).Hours()/24))
+}
+
+func renderInactivityReport(days int) string {
+	inactive := listInactiveMembers(days)
+
+	msg := fmt.Sprintf("**[%d일 이상 비활동 길드원]** %d명\n", days, len(inactive))
+	if len(inactive) == 0 {
+		return msg + "비활동 길드원이 없습니다.\n"
+	}
+	for idx, im := range inactive {
+		line := fmt.Sprintf("* %s (%s / Lv.%d) - %s\n", im.Info.Nickname, im.Info.SubRoleName, im.Info.Level, describeLastActivity(im.LastActivity))
+		if utf8.RuneCountInString(msg+line) > inactivityReportLimit {
+			msg += fmt.Sprintf("외 %d명\n", len(inactive)-idx)
+			break
+		}
+		msg += line
+	}
+	return msg
+}
+
+func printInactivityReport(s *discordgo.Session, i *discordgo.Interaction, days int, notice string) {
+	msg, components := renderInactivityReportMessage(days, notice)
+	s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: components,
+		},
+	})
+}
+
+func renderInactivityReportMessage(days int, notice string) (string, []discordgo.MessageComponent) {
+	var buttons []discordgo.MessageComponent
+	for _, d := range inactivityPeriods {
+		style := discordgo.SecondaryButton
+		if d == days {
+			style = discordgo.PrimaryButton
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    fmt.Sprintf("%d일", d),
+			Style:    style,
+			CustomID: fmt.Sprintf("inactive-report_%d", d),
+		})
+	}
+
+	msg := renderInactivityReport(days)
+	if notice != "" {
+		msg = notice + "\n\n" + msg
+	}
+
+	return msg, []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: buttons,
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "체크인 DM 보내기",
+					Style:    discordgo.DangerButton,
+					CustomID: fmt.Sprintf("inactive-checkin_%d", days),
+				},
+				discordgo.Button{
+					Label:    "처음으로 돌아가기",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "landing-page",
+				},
+			},
+		},
+	}
+}
+
+// sendInactivityCheckIns DMs a check-in to the members inactive for the days who have no open check-in,
+// and returns how many were sent. Members without any recorded activity are only included when asked.
+func sendInactivityCheckIns(s *discordgo.Session, days int, includeUnrecorded bool) int {
+	sent := 0
+	for _, im := range listInactiveMembers(days) {
+		if im.LastActivity.IsZero() && !includeUnrecorded {
+			continue
+		}
+
+		var open int64
+		adb.Model(&model.InactivityCheckIn{}).Where("discord_user_id = ? AND resolved = ?", im.UserID, false).Count(&open)
+		if open > 0 {
+			continue
+		}
+
+		c, err := s.UserChannelCreate(im.UserID)
+		if err != nil {
+			fmt.Println("failed to create user channel:", err)
+			continue
+		}
+		_, err = s.ChannelMessageSendComplex(c.ID, &discordgo.MessageSend{
+			Content: fmt.Sprintf("안녕하세요 %s 님, 영원길드입니다.\n최근 %d일 이상 디스코드 활동이 없어 안부를 여쭙니다. 계속 길드 활동을 하실 예정이라면 아래 버튼을 눌러주세요.\n%s 안에 응답이나 활동이 없으면 길드 정리 대상으로 운영진에게 전달됩니다.",
+				im.Info.Nickname, days, formatCheckInGracePeriod()),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "활동 중이에요",
+							Style:    discordgo.PrimaryButton,
+							CustomID: "inactive-ack_" + im.UserID,
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			fmt.Println("failed to send check-in message:", err)
+			continue
+		}
+
+		// only a delivered check-in starts the grace period
+		adb.Create(&model.InactivityCheckIn{
+			DiscordUserID: im.UserID,
+			Nickname:      im.Info.Nickname,
+			SentAt:        time.Now().UTC(),
+		})
+		sent++
+	}
+	return sent
+}
+
+func checkInGracePeriod() time.Duration {
+	d, err := time.ParseDuration(environment.InactivityCheckInGracePeriod)
+	if err != nil || d <= 0 {
+		fmt.Println("invalid inactivity check-in grace period:", environment.InactivityCheckInGracePeriod)
+		return 72 * time.Hour
+	}
+	return d
+}
+
+func formatCheckInGracePeriod() string {
+	d := checkInGracePeriod()
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d일", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%d시간", int(d.Hours()))
+}
+
+// InactivityCheckInRefresh sends the automatic check-ins when configured, resolves check-ins of members who became active,
+// and proposes the members who did not respond within the grace period for removal in the admin channel.
+func InactivityCheckInRefresh(dg *discordgo.Session) error {
+	if environment.InactivityAutoCheckInDays != "" {
+		days, err := strconv.Atoi(environment.InactivityAutoCheckInDays)
+		if err != nil || days <= 0 {
+			return fmt.Errorf("invalid inactivity auto check-in days: %s", environment.InactivityAutoCheckInDays)
+		}
+		// members without any record may just predate the activity tracking, leave them to the officers
+		sendInactivityCheckIns(dg, days, false)
+	}
+
+	var checkIns []model.InactivityCheckIn
+	adb.Where("resolved = ?", false).Find(&checkIns)
+	if len(checkIns) == 0 {
+		return nil
+	}
+
+	last := lastActivityByMention()
+	grace := checkInGracePeriod()
+	for _, c := range checkIns {
+		// already proposed, waiting for the officers
+		if !c.ProposedAt.IsZero() {
+			continue
+		}
+
+		// left the guild
+		member := cache.GetGuildMember(c.DiscordUserID)
+		if member == nil {
+			c.Resolved = true
+			adb.Save(&c)
+			continue
+		}
+
+		// active again
+		if last[fmt.Sprintf("<@%s>", c.DiscordUserID)].After(c.SentAt) {
+			c.Resolved = true
+			adb.Save(&c)
+			continue
+		}
+
+		if time.Since(c.SentAt) < grace {
+			continue
+		}
+
+		desc := c.Nickname
+		if m, err := GetMainMemberInfo(member); err == nil {
+			desc = fmt.Sprintf("%s (%s / Lv.%d)", m.Nickname, m.SubRoleName, m.Level)
+		}
+		_, err := dg.ChannelMessageSendComplex(environment.DiscordGuildPollChannelID, &discordgo.MessageSend{
+			Content: fmt.Sprintf("**[길드 정리 제안]** <@%s> %s\n%s 에 보낸 체크인 DM에 %s 동안 응답과 활동이 없었습니다. %s",
+				c.DiscordUserID, desc, c.SentAt.In(loc).Format("2006-01-02 15:04"), formatCheckInGracePeriod(),
+				describeLastActivity(last[fmt.Sprintf("<@%s>", c.DiscordUserID)])),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "유지",
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("inactive-keep_%d", c.ID),
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send removal proposal: %w", err)
+		}
+		c.ProposedAt = time.Now().UTC()
+		adb.Save(&c)
+	}
+	return nil
+}
+
+func inactivityHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	args := strings.Split(i.MessageComponentData().CustomID, "_")
+	if len(args) < 2 {
+		return
+	}
+	switch args[0] {
+	case "inactive-report":
+		days, err := strconv.Atoi(args[1])
+		if err != nil {
+			return
+		}
+		printInactivityReport(s, i.Interaction, days, "")
+	case "inactive-checkin":
+		days, err := strconv.Atoi(args[1])
+		if err != nil {
+			return
+		}
+
+		// sending the DMs takes longer than the interaction response window
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		})
+		sent := sendInactivityCheckIns(s, days, true)
+		msg, components := renderInactivityReportMessage(days, fmt.Sprintf("%d명에게 체크인 DM을 보냈습니다. (이미 체크인 중인 길드원 제외)", sent))
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content:    &msg,
+			Components: &components,
+		})
+	case "inactive-ack":
+		// the open check-in of the member who pressed the button
+		var c model.InactivityCheckIn
+		if adb.Where("discord_user_id = ? AND resolved = ?", interactionUserID(i), false).Order("id desc").Limit(1).Find(&c).RowsAffected == 0 {
+			return
+		}
+		c.Resolved = true
+		c.RespondedAt = time.Now().UTC()
+		adb.Save(&c)
+		updateGuildActivity(c.DiscordUserID)
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "응답해주셔서 감사합니다. 앞으로도 영원길드에서 즐거운 시간 보내세요!",
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+	case "inactive-keep":
+		var c model.InactivityCheckIn
+		if err := adb.First(&c, args[1]).Error; err != nil {
+			return
+		}
+		c.Resolved = true
+		adb.Save(&c)
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    i.Message.Content + fmt.Sprintf("\n→ <@%s> 님이 유지로 처리했습니다.", interactionUserID(i)),
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"time"
+)
+
+// levelMonthlyReportMaxLines is how many members the monthly leaderboard shows.
+const levelMonthlyReportMaxLines = 10
+
+// levelMilestones are the levels announced with special formatting.
+var levelMilestones = map[int]string{
+	120: "4차 전직 달성",
+	150: "150 레벨 달성",
+	200: "만렙 달성",
+}
+
+// crossedLevelMilestone returns the highest milestone in (from, to].
+func crossedLevelMilestone(from, to int) (int, bool) {
+	milestone := 0
+	for lv := range levelMilestones {
+		if lv > from && lv <= to && lv > milestone {
+			milestone = lv
+		}
+	}
+	return milestone, milestone > 0
+}
+
+// announceLevelUp posts a congratulation for the level-up to the level-up channel, if configured.
+// Only a level higher than the highest one already announced for the character is announced,
+// so lowering and raising the level again or correcting a typo does not repeat the announcement.
+func announceLevelUp(dg *discordgo.Session, userID string, m model.MemberInfo, from int) {
+	if environment.DiscordGuildLevelUpChannelID == "" {
+		return
+	}
+
+	var announced model.LevelAnnouncement
+	found := ldb.Where("discord_user_id = ? AND nickname = ?", userID, m.Nickname).Limit(1).Find(&announced).RowsAffected > 0
+	if found {
+		if m.Level <= announced.Level {
+			return
+		}
+		if announced.Level > from {
+			from = announced.Level
+		}
+	}
+
+	msg := fmt.Sprintf("🎉 %s (%s) 님이 Lv.%d → Lv.%d 레벨업 했습니다! 축하합니다!", m.Mention, m.SubRoleName, from, m.Level)
+	if lv, ok := crossedLevelMilestone(from, m.Level); ok {
+		msg = fmt.Sprintf("🏆 **[%s]** 🏆\n%s (%s) 님이 드디어 **Lv.%d**에 도달했습니다! 모두 축하해주세요! 🎊",
+			levelMilestones[lv], m.Mention, m.SubRoleName, lv)
+		if m.Level > lv {
+			msg += fmt.Sprintf(" (현재 Lv.%d)", m.Level)
+		}
+	}
+
+	if _, err := dg.ChannelMessageSend(environment.DiscordGuildLevelUpChannelID, msg); err != nil {
+		fmt.Println("failed to send level-up announcement:", err)
+		return
+	}
+
+	if !found {
+		announced = model.LevelAnnouncement{DiscordUserID: userID, Nickname: m.Nickname}
+	}
+	announced.Level = m.Level
+	ldb.Save(&announced)
+}
+
+// LevelMonthlyReport posts last month's most levels gained leaderboard to the level-up channel once a month, on the 1st morning.
+func LevelMonthlyReport(dg *discordgo.Session) error {
+	if environment.DiscordGuildLevelUpChannelID == "" {
+		return nil
+	}
+
+	now := time.Now().In(loc)
+	if now.Day() != 1 || now.Hour() < 9 {
+		return nil
+	}
+
+	until := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	since := until.AddDate(0, -1, 0)
+	month := since.Format("2006-01")
+
+	var count int64
+	ldb.Model(&model.LevelReportPost{}).Where("month = ?", month).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("**[%s 월간 레벨업 순위]**\n\n", since.Format("2006년 01월"))
+	ranked := 0
+	for _, g := range listLevelGrowths(since, until) {
+		if g.gained() <= 0 || ranked >= levelMonthlyReportMaxLines {
+			break
+		}
+		ranked++
+		msg += fmt.Sprintf("%d. %s (%s) Lv.%d → Lv.%d (+%d)\n", ranked, g.Nickname, g.SubRoleName, g.From, g.To, g.gained())
+	}
+	if ranked == 0 {
+		msg += "지난달에 기록된 레벨업이 없습니다.\n"
+	}
+
+	if err := sendSplitMessage(dg, environment.DiscordGuildLevelUpChannelID, msg); err != nil {
+		return fmt.Errorf("failed to send monthly level report: %w", err)
+	}
+	ldb.Create(&model.LevelReportPost{Month: month})
+	return nil
+}
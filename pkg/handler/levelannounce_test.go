@@ -0,0 +1,29 @@
+package handler
+
+import "testing"
+
+func TestCrossedLevelMilestone(t *testing.T) {
+	if lv, ok := crossedLevelMilestone(119, 120); !ok || lv != 120 {
+		t.Errorf("reaching 120 exactly: got %d, %v", lv, ok)
+	}
+	if lv, ok := crossedLevelMilestone(140, 155); !ok || lv != 150 {
+		t.Errorf("passing over 150: got %d, %v", lv, ok)
+	}
+	// a jump over several milestones announces only the highest one
+	if lv, ok := crossedLevelMilestone(110, 200); !ok || lv != 200 {
+		t.Errorf("jumping to 200: got %d, %v", lv, ok)
+	}
+	if lv, ok := crossedLevelMilestone(120, 125); ok {
+		t.Errorf("already at 120: got %d, want no milestone", lv)
+	}
+}
+
+func TestCrossedLevelMilestoneNeverGoingDown(t *testing.T) {
+	for from := 1; from <= 200; from++ {
+		for to := 1; to <= from; to++ {
+			if lv, ok := crossedLevelMilestone(from, to); ok {
+				t.Fatalf("crossedLevelMilestone(%d, %d) = %d, want none", from, to, lv)
+			}
+		}
+	}
+}
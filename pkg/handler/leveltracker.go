@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ldb *gorm.DB
+
+const (
+	// levelHistoryMaxLines is how many of the latest level-ups the history shows.
+	levelHistoryMaxLines = 15
+	// levelRankMaxLines is how many members the growth ranking shows.
+	levelRankMaxLines = 20
+	// levelGrowthDefaultDays is the period of the growth rank shown with the history.
+	levelGrowthDefaultDays = 30
+)
+
+var levelGrowthPeriods = []int{7, 30, 90}
+
+type levelGrowth struct {
+	UserID      string
+	Nickname    string
+	SubRoleName string
+	From        int
+	To          int
+}
+
+func (g levelGrowth) gained() int {
+	return g.To - g.From
+}
+
+func LevelTrackerInit(dg *discordgo.Session) error {
+	var err error
+	ldb, err = gorm.Open(sqlite.Open(environment.LevelTrackerSQLitePath), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	err = ldb.AutoMigrate(&model.LevelSnapshot{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = ldb.AutoMigrate(&model.LevelReportPost{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = ldb.AutoMigrate(&model.LevelAnnouncement{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	if err := RegisterLevelCommand(dg); err != nil {
+		return err
+	}
+
+	dg.AddHandler(levelHandler)
+	return nil
+}
+
+func LevelTrackerFinalize() {
+	sqlDB, err := ldb.DB()
+	if err != nil {
+		fmt.Println("failed to get db connection for close: %w", err)
+		return
+	}
+	_ = sqlDB.Close()
+}
+
+func RegisterLevelCommand(dg *discordgo.Session) error {
+	commands := []*discordgo.ApplicationCommand{
+		{
+			Name:        "레벨",
+			Description: "레벨업 기록 및 성장 순위 명령어",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "길드원",
+					Description: "기록을 볼 길드원 (비우면 본인)",
+					Required:    false,
+				},
+			},
+		},
+	}
+
+	for _, cmd := range commands {
+		_, err := dg.ApplicationCommandCreate(
+			dg.State.User.ID,
+			"",
+			cmd,
+		)
+		if err != nil {
+			fmt.Printf("Cannot create '%v' command: %v\n", cmd.Name, err)
+			return err
+		}
+
+		fmt.Printf("Registered command: /%s\n", cmd.Name)
+	}
+
+	return nil
+}
+
+// LevelSnapshotRefresh records the level of every member's main character when it has changed since the last snapshot,
+// and announces the level-ups.
+func LevelSnapshotRefresh(dg *discordgo.Session) error {
+	now := time.Now().UTC()
+	for _, member := range cache.ListAllMembers() {
+		m, err := GetMainMemberInfo(member)
+		if err != nil {
+			continue
+		}
+
+		var last model.LevelSnapshot
+		err = ldb.Where("discord_user_id = ? AND nickname = ?", member.User.ID, m.Nickname).Order("recorded_at desc").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get last level snapshot: %w", err)
+		}
+		if err == nil && last.Level == m.Level {
+			continue
+		}
+
+		ldb.Create(&model.LevelSnapshot{
+			DiscordUserID: member.User.ID,
+			Nickname:      m.Nickname,
+			SubRoleName:   m.SubRoleName,
+			Level:         m.Level,
+			RecordedAt:    now,
+		})
+
+		// the first snapshot of a character is not a level-up
+		if err == nil && m.Level > last.Level {
+			announceLevelUp(dg, member.User.ID, *m, last.Level)
+		}
+	}
+	return nil
+}
+
+// listLevelSnapshots returns the snapshots of the character, oldest first.
+func listLevelSnapshots(userID, nickname string) []model.LevelSnapshot {
+	var snapshots []model.LevelSnapshot
+	ldb.Where("discord_user_id = ? AND nickname = ?", userID, nickname).Order("recorded_at asc").Find(&snapshots)
+	return snapshots
+}
+
+// listLevelGrowths returns the levels gained by the main character of every member in [since, until), most gained first.
+// The baseline is the last snapshot at or before since, or the first snapshot when tracking started later.
+func listLevelGrowths(since, until time.Time) []levelGrowth {
+	var growths []levelGrowth
+	for _, member := range cache.ListAllMembers() {
+		m, err := GetMainMemberInfo(member)
+		if err != nil {
+			continue
+		}
+		snapshots := listLevelSnapshots(member.User.ID, m.Nickname)
+		if len(snapshots) == 0 || !snapshots[0].RecordedAt.Before(until) {
+			continue
+		}
+
+		from, to := snapshots[0].Level, snapshots[0].Level
+		for _, sn := range snapshots {
+			if !sn.RecordedAt.Before(until) {
+				break
+			}
+			if !sn.RecordedAt.After(since) {
+				from = sn.Level
+			}
+			to = sn.Level
+		}
+		growths = append(growths, levelGrowth{
+			UserID:      member.User.ID,
+			Nickname:    m.Nickname,
+			SubRoleName: m.SubRoleName,
+			From:        from,
+			To:          to,
+		})
+	}
+
+	sort.SliceStable(growths, func(i, j int) bool {
+		if growths[i].gained() == growths[j].gained() {
+			if growths[i].To == growths[j].To {
+				return growths[i].Nickname < growths[j].Nickname
+			}
+			return growths[i].To > growths[j].To
+		}
+		return growths[i].gained() > growths[j].gained()
+	})
+	return growths
+}
+
+// renderLevelHistory renders the level-ups, days per level and growth rank of the member's main character.
+func renderLevelHistory(member *discordgo.Member) string {
+	m, err := GetMainMemberInfo(member)
+	if err != nil {
+		return "닉네임이나 직업 역할을 확인할 수 없는 길드원입니다."
+	}
+
+	msg := fmt.Sprintf("**[%s 님의 레벨 기록]** (%s / Lv.%d)\n", m.Nickname, m.SubRoleName, m.Level)
+	snapshots := listLevelSnapshots(member.User.ID, m.Nickname)
+	if len(snapshots) == 0 {
+		return msg + "아직 기록된 레벨이 없습니다. 레벨은 30분마다 기록됩니다.\n"
+	}
+	msg += fmt.Sprintf("* 기록 시작: %s (Lv.%d)\n", snapshots[0].RecordedAt.In(loc).Format("2006-01-02"), snapshots[0].Level)
+
+	// level-ups with the days taken per level, levels skipped between snapshots share the days evenly
+	var lines []string
+	var totalDays float64
+	var levelUps int
+	for idx := 1; idx < len(snapshots); idx++ {
+		prev, cur := snapshots[idx-1], snapshots[idx]
+		gained := cur.Level - prev.Level
+		if gained <= 0 {
+			continue
+		}
+		days := cur.RecordedAt.Sub(prev.RecordedAt).Hours() / 24
+		totalDays += days
+		levelUps += gained
+		lines = append(lines, fmt.Sprintf("* %s Lv.%d → Lv.%d (레벨당 %.1f일)",
+			cur.RecordedAt.In(loc).Format("2006-01-02"), prev.Level, cur.Level, days/float64(gained)))
+	}
+
+	if len(lines) == 0 {
+		msg += "\n기록 시작 이후 레벨업 기록이 없습니다.\n"
+	} else {
+		if len(lines) > levelHistoryMaxLines {
+			lines = lines[len(lines)-levelHistoryMaxLines:]
+		}
+		msg += "\n**레벨업 기록**\n"
+		msg += strings.Join(lines, "\n") + "\n"
+		msg += fmt.Sprintf("* 평균 레벨업 소요: %.1f일 (총 %d레벨)\n", totalDays/float64(levelUps), levelUps)
+	}
+
+	since := time.Now().AddDate(0, 0, -levelGrowthDefaultDays)
+	growths := listLevelGrowths(since, time.Now())
+	for idx, g := range growths {
+		if g.UserID == member.User.ID {
+			msg += fmt.Sprintf("* 최근 %d일 성장: +%d레벨, 길드 %d위 / %d명\n", levelGrowthDefaultDays, g.gained(), idx+1, len(growths))
+			break
+		}
+	}
+	return msg
+}
+
+func renderLevelRank(days int) string {
+	growths := listLevelGrowths(time.Now().AddDate(0, 0, -days), time.Now())
+
+	msg := fmt.Sprintf("**[최근 %d일 성장 순위]**\n", days)
+	if len(growths) == 0 {
+		return msg + "아직 기록된 레벨이 없습니다.\n"
+	}
+	for idx, g := range growths {
+		if idx >= levelRankMaxLines {
+			break
+		}
+		msg += fmt.Sprintf("%d. %s (%s) Lv.%d → Lv.%d (+%d)\n", idx+1, g.Nickname, g.SubRoleName, g.From, g.To, g.gained())
+	}
+	return msg
+}
+
+func levelComponents(userID string) []discordgo.MessageComponent {
+	var buttons []discordgo.MessageComponent
+	for _, days := range levelGrowthPeriods {
+		buttons = append(buttons, discordgo.Button{
+			Label:    fmt.Sprintf("%d일 성장 순위", days),
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("level-rank_%d_%s", days, userID),
+		})
+	}
+	buttons = append(buttons, discordgo.Button{
+		Label:    "레벨 기록",
+		Style:    discordgo.PrimaryButton,
+		CustomID: "level-history_" + userID,
+	})
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+func levelHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		data, ok := i.Data.(discordgo.ApplicationCommandInteractionData)
+		if !ok || data.Name != "레벨" {
+			return
+		}
+
+		userID := interactionUserID(i)
+		for _, opt := range data.Options {
+			if opt.Name == "길드원" {
+				userID = opt.UserValue(nil).ID
+			}
+		}
+
+		member := cache.GetGuildMember(userID)
+		if member == nil {
+			respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+			return
+		}
+
+		var flags discordgo.MessageFlags
+		if i.GuildID != "" {
+			flags = discordgo.MessageFlagsEphemeral
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:    renderLevelHistory(member),
+				Components: levelComponents(userID),
+				Flags:      flags,
+			},
+		})
+	case discordgo.InteractionMessageComponent:
+		args := strings.Split(i.MessageComponentData().CustomID, "_")
+		switch args[0] {
+		case "level-history":
+			member := cache.GetGuildMember(args[1])
+			if member == nil {
+				respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+				return
+			}
+			levelUpdateMessage(s, i, renderLevelHistory(member), args[1])
+		case "level-rank":
+			days, err := strconv.Atoi(args[1])
+			if err != nil {
+				return
+			}
+			levelUpdateMessage(s, i, renderLevelRank(days), args[2])
+		}
+	}
+}
+
+func levelUpdateMessage(s *discordgo.Session, i *discordgo.InteractionCreate, msg string, userID string) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: levelComponents(userID),
+		},
+	})
+}
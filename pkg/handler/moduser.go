@@ -120,6 +120,15 @@ func printLandingPage(s *discordgo.Session, i *discordgo.Interaction, update boo
 						},
 					},
 				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "비활동 리포트",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "inactive-report_14",
+						},
+					},
+				},
 			},
 		},
 	})
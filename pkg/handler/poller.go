@@ -799,7 +799,7 @@ func getPollMemberMap(results []PollResult) map[string][]model.MemberInfo {
 			fmt.Printf("not existing guild member: %s\n", r.DiscordUserID)
 			continue
 		}
-		member, err := GetMemberInfoFromMember(m)
+		member, err := GetMainMemberInfo(m)
 		if err != nil {
 			return nil
 		}
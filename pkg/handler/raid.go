@@ -27,16 +27,31 @@ func RaidSubscriptionRefresh(dg *discordgo.Session) error {
 	for _, sc := range schedules {
 		// find attends
 		var attends []model.RaidAttend
-		rdb.Where("raid_schedule_id = ?", sc.ID).Find(&attends)
+		rdb.Where("raid_schedule_id = ?", sc.ID).Order("id asc").Find(&attends)
 
 		// populate member list
 		memberListByRole := map[string][]string{}
+		var backups []string
+		var waitlist []string
+		attendCount := 0
 		for _, a := range attends {
+			if a.Canceled {
+				continue
+			}
+			if a.Waitlisted {
+				waitlist = append(waitlist, fmt.Sprintf("%d. %s%s", len(waitlist)+1, a.Mention, describeAttendPreference(a)))
+				continue
+			}
+			if attendPreference(a) == model.RaidAttendPreferenceBackup {
+				backups = append(backups, fmt.Sprintf("* %s (%s)%s", a.Mention, a.SubRoleName, noteSuffix(a)))
+				continue
+			}
+			attendCount++
 			role := a.SubRoleName
 			if memberListByRole[role] == nil {
 				memberListByRole[role] = make([]string, 0)
 			}
-			memberListByRole[role] = append(memberListByRole[role], fmt.Sprintf("* %s", a.Mention))
+			memberListByRole[role] = append(memberListByRole[role], fmt.Sprintf("* %s%s", a.Mention, describeAttendPreference(a)))
 		}
 
 		// extract key and sort
@@ -54,11 +69,45 @@ func RaidSubscriptionRefresh(dg *discordgo.Session) error {
 			sc.StartTime.In(loc).Format("01월 02일"), sc.TryCount, sc.StartTime.In(loc).Format("15:04"),
 			sc.SubscriptionEndTime.In(loc).Format("01월 02일 15:04"))
 
+		if sc.Capacity > 0 {
+			msg += fmt.Sprintf("정원 %d/%d명", attendCount, sc.Capacity)
+			if sc.JobCapacity != "" {
+				msg += fmt.Sprintf(" (직업별 정원 %s)", sc.JobCapacity)
+			}
+			msg += "\n\n"
+		} else if sc.JobCapacity != "" {
+			msg += fmt.Sprintf("직업별 정원 %s\n\n", sc.JobCapacity)
+		}
+
+		if rt, ok := getRaidType(sc.Raid); ok {
+			var confirmed []model.RaidAttend
+			for _, a := range attends {
+				if !a.Canceled && !a.Waitlisted && attendPreference(a) != model.RaidAttendPreferenceBackup {
+					confirmed = append(confirmed, a)
+				}
+			}
+			msg += fmt.Sprintf("최소 레벨 %d", rt.MinLevel)
+			if missing := missingRequiredJobs(rt, confirmed); len(missing) > 0 {
+				msg += fmt.Sprintf(", 부족한 직업: %s", strings.Join(missing, ", "))
+			}
+			msg += "\n\n"
+		}
+
 		for _, k := range keys {
 			msg += fmt.Sprintf("**%s**\n", k)
 			msg += strings.Join(memberListByRole[k], "\n")
 			msg += "\n\n"
 		}
+		if len(backups) > 0 {
+			msg += "**예비**\n"
+			msg += strings.Join(backups, "\n")
+			msg += "\n\n"
+		}
+		if len(waitlist) > 0 {
+			msg += "**대기자**\n"
+			msg += strings.Join(waitlist, "\n")
+			msg += "\n\n"
+		}
 		msg += "~~                                        ~~"
 
 		// get latest message
@@ -68,12 +117,18 @@ func RaidSubscriptionRefresh(dg *discordgo.Session) error {
 			continue
 		}
 
-		if cmsg.Content == msg {
+		if cmsg.Content == msg && len(cmsg.Components) > 0 {
 			continue
 		}
 
-		// send message
-		_, err = dg.ChannelMessageEdit(environment.DiscordGuildRaidSubscriptionChannelID, sc.MessageID, msg)
+		// send message, keeping the sign-up buttons
+		components := raidSubscriptionComponents(sc.ID)
+		_, err = dg.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			Channel:    environment.DiscordGuildRaidSubscriptionChannelID,
+			ID:         sc.MessageID,
+			Content:    &msg,
+			Components: &components,
+		})
 		if err != nil {
 			fmt.Println("failed to edit message:", err)
 			continue
@@ -132,14 +187,13 @@ func RaidRoleMappingRefresh(dg *discordgo.Session) error {
 
 		// get attends
 		var attends []model.RaidAttend
-		rdb.Where("raid_schedule_id = ? AND canceled = ?", sc.ID, false).Find(&attends)
+		rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", sc.ID, false, false).Find(&attends)
 
 		// assign role
 		for _, a := range attends {
-			nmap := cache.ListAllMembersNicknameMap()
-			m, ok := nmap[a.Nickname]
-			if !ok {
-				fmt.Println("failed to get member id from nickname")
+			m := cache.GetGuildMember(userIDFromMention(a.Mention))
+			if m == nil {
+				fmt.Println("failed to get member from mention")
 				continue
 			}
 
@@ -223,6 +277,33 @@ func RaidRoleMappingRefresh(dg *discordgo.Session) error {
 	return nil
 }
 
+// createRaidSchedule posts the member attend message to the subscription channel and saves the schedule.
+func createRaidSchedule(dg *discordgo.Session, schedule *model.RaidSchedule) error {
+	t := schedule.StartTime.In(loc)
+	m, err := dg.ChannelMessageSend(environment.DiscordGuildRaidSubscriptionChannelID, fmt.Sprintf("**%s - %d트라이 (%s 출발)**",
+		t.Format("01월 02일"), schedule.TryCount, t.Format("15:04")))
+	if err != nil {
+		return fmt.Errorf("failed to send subscription message: %w", err)
+	}
+
+	schedule.MessageID = m.ID
+	if err := rdb.Create(schedule).Error; err != nil {
+		return fmt.Errorf("failed to create raid schedule: %w", err)
+	}
+
+	// attach sign-up buttons now that the schedule id is known
+	components := raidSubscriptionComponents(schedule.ID)
+	_, err = dg.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    environment.DiscordGuildRaidSubscriptionChannelID,
+		ID:         m.ID,
+		Components: &components,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach sign-up buttons: %w", err)
+	}
+	return nil
+}
+
 func RaidInfoRefresh(dg *discordgo.Session) error {
 	return nil
 }
@@ -244,16 +325,79 @@ func RaidInit(dg *discordgo.Session) error {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	err = rdb.AutoMigrate(&model.RaidScheduleTemplate{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.RaidTemplateSkip{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	err = rdb.AutoMigrate(&model.RaidAttend{})
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	err = rdb.AutoMigrate(&model.RaidReminder{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	err = rdb.AutoMigrate(&model.RaidInfo{})
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	err = rdb.AutoMigrate(&model.RaidPartyInfo{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.RaidPartyMemberInfo{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.DistributionRule{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.RaidPayout{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.RaidAttendance{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.RaidAttendLedger{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.RaidReportPost{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.RaidCoManager{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = rdb.AutoMigrate(&model.RaidCalendarToken{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	migrateLegacyRaidTypes()
+	loadRaidReminderOffsets()
+
 	// add watchers
 	dg.AddHandler(raidScheduleHandler)
 	return nil
@@ -318,7 +462,7 @@ func raidScheduleUserInitialHandler(s *discordgo.Session, i *discordgo.Interacti
 	if memberInfo == nil {
 		return
 	}
-	m, err := GetMemberInfoFromMember(memberInfo)
+	m, err := GetMainMemberInfo(memberInfo)
 	if err != nil {
 		return
 	}
@@ -335,7 +479,11 @@ func raidScheduleUserInitialHandler(s *discordgo.Session, i *discordgo.Interacti
 
 		// 다가오는 스케줄만 보여주기
 		if a.RaidSchedule.StartTime.After(time.Now()) {
-			attendList = append(attendList, fmt.Sprintf("[%s] %s (%d트라이)", raidName, raidStartTime, raidTryCount))
+			item := fmt.Sprintf("[%s] %s (%d트라이)", raidName, raidStartTime, raidTryCount)
+			if a.Waitlisted {
+				item += fmt.Sprintf(" - 대기 %d번", waitlistPosition(a))
+			}
+			attendList = append(attendList, item)
 		}
 	}
 
@@ -369,6 +517,16 @@ func raidScheduleUserInitialHandler(s *discordgo.Session, i *discordgo.Interacti
 							Style:    discordgo.DangerButton,
 							CustomID: "user-cancel-schedule",
 						},
+						discordgo.Button{
+							Label:    "내 기록",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "user-history",
+						},
+						discordgo.Button{
+							Label:    "캘린더",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "user-calendar",
+						},
 					},
 				},
 			},
@@ -440,6 +598,30 @@ func raidScheduleAdminInitialHandler(s *discordgo.Session, i *discordgo.Interact
 						},
 					},
 				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "반복 일정",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "admin-template",
+						},
+						discordgo.Button{
+							Label:    "참석 통계",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "admin-stats_30",
+						},
+						discordgo.Button{
+							Label:    "레이드 리포트",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "admin-report",
+						},
+						discordgo.Button{
+							Label:    "레이드 관리자",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "admin-manager",
+						},
+					},
+				},
 			},
 		},
 	})
@@ -447,6 +629,9 @@ func raidScheduleAdminInitialHandler(s *discordgo.Session, i *discordgo.Interact
 
 func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.InteractionCreate, actionID string) {
 	args := strings.Split(actionID, "_")
+	if !authorizeRaidAction(s, i, args) {
+		return
+	}
 	if len(args) == 1 {
 		switch args[0] {
 		case "user-landing-page":
@@ -455,6 +640,34 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 			raidScheduleAdminInitialHandler(s, i, true)
 		case "admin-add-new-raid":
 			discord.SendNewRaidModal(s, i.Interaction)
+		case "user-history":
+			raidMyHistoryHandler(s, i)
+		case "user-calendar":
+			raidCalendarHandler(s, i)
+		case "user-calendar-rotate":
+			raidCalendarRotateHandler(s, i)
+		case "admin-report":
+			raidReportHandler(s, i, "")
+		case "admin-manager":
+			raidManagerListHandler(s, i)
+		case "admin-template":
+			raidTemplateListHandler(s, i, "")
+		case "admin-template-select":
+			raidTemplateDetailHandler(s, i, i.MessageComponentData().Values[0])
+		case "admin-template-add":
+			// get existing raids
+			var raids []model.Raid
+			rdb.Find(&raids)
+
+			// list raids
+			raidSelectionMap := make(map[string]string)
+			for _, r := range raids {
+				raidSelectionMap[r.RaidName] = "admin-template-add-select-raid_" + fmt.Sprintf("%d", r.ID)
+			}
+			raidSelectionMap["반복 일정으로 돌아가기"] = "admin-template"
+
+			// send message
+			discord.SendInteractionWithButtons(s, i.Interaction, "반복 일정을 추가 할 레이드를 선택하세요.", raidSelectionMap, true)
 		case "admin-add-schedule":
 			// get existing raids
 			var raids []model.Raid
@@ -696,7 +909,11 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 			// create user list
 			var attendList []string
 			for _, a := range attends {
-				attendList = append(attendList, fmt.Sprintf("%s / %d / %s", a.MemberInfo.SubRoleName, a.Level, a.Nickname))
+				item := fmt.Sprintf("%s / %d / %s", a.MemberInfo.SubRoleName, a.Level, a.Nickname)
+				if a.Waitlisted {
+					item += " (대기)"
+				}
+				attendList = append(attendList, item)
 			}
 			attendListStr := strings.Join(attendList, "\n")
 
@@ -747,14 +964,14 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 			if memberInfo == nil {
 				return
 			}
-			m, err := GetMemberInfoFromMember(memberInfo)
+			m, err := GetMainMemberInfo(memberInfo)
 			if err != nil {
 				return
 			}
 
 			// list attend by user
 			var attends []model.RaidAttend
-			err = rdb.Where("nickname = ?", m.Nickname).Find(&attends).Error
+			err = rdb.Where("mention = ?", m.Mention).Find(&attends).Error
 			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 				return
 			}
@@ -781,10 +998,17 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 
 				// subscriptionEndTime이 지나지 않은 스케줄만 보여주기
 				if sc.SubscriptionEndTime.After(time.Now()) {
-					selectOptions = append(selectOptions, discordgo.SelectMenuOption{
+					option := discordgo.SelectMenuOption{
 						Label: fmt.Sprintf("[%s] %s (%d트라이)", raidName, raidStartTime, raidTryCount),
 						Value: fmt.Sprintf("%d", sc.ID),
-					})
+					}
+					if rt, ok := getRaidType(sc.Raid); ok {
+						option.Description = fmt.Sprintf("최소 레벨 %d", rt.MinLevel)
+						if m.Level < rt.MinLevel {
+							option.Description += " (레벨 미달)"
+						}
+					}
+					selectOptions = append(selectOptions, option)
 				}
 			}
 
@@ -860,54 +1084,15 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 			if memberInfo == nil {
 				return
 			}
-			m, err := GetMemberInfoFromMember(memberInfo)
-			if err != nil {
-				return
-			}
-
-			// check if already attended
-			var attend model.RaidAttend
-			err = rdb.Where("nickname = ? AND raid_schedule_id = ?", m.Nickname, schedule.ID).First(&attend).Error
-			if err == nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseUpdateMessage,
-					Data: &discordgo.InteractionResponseData{
-						Flags: discordgo.MessageFlagsEphemeral,
-						Content: fmt.Sprintf("[%s] %s (%d 트라이) 에 이미 참가하고 있습니다.",
-							schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount),
-					},
-				})
-				return
-			}
-			if !errors.Is(err, gorm.ErrRecordNotFound) {
-				return
-			}
 
-			// create new attend
-			newAttend := model.RaidAttend{
-				MemberInfo:     *m,
-				RaidScheduleID: schedule.ID,
-				Canceled:       false,
-			}
-			rdb.Create(&newAttend)
+			content, components := raidJoinPrompt(schedule, memberInfo, "")
 
 			// send message
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseUpdateMessage,
 				Data: &discordgo.InteractionResponseData{
-					Content: fmt.Sprintf("[%s] %s (%d 트라이) 참가 신청이 완료되었습니다.",
-						schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount),
-					Components: []discordgo.MessageComponent{
-						discordgo.ActionsRow{
-							Components: []discordgo.MessageComponent{
-								discordgo.Button{
-									Label:    "참가 신청으로 돌아가기",
-									Style:    discordgo.PrimaryButton,
-									CustomID: "user-attend-schedule",
-								},
-							},
-						},
-					},
+					Content:    content,
+					Components: raidAttendBackComponents(components),
 				},
 			})
 		case "user-cancel-schedule":
@@ -916,14 +1101,14 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 				return
 			}
 
-			m, err := GetMemberInfoFromMember(memberInfo)
+			m, err := GetMainMemberInfo(memberInfo)
 			if err != nil {
 				return
 			}
 
 			// list attend
 			var attends []model.RaidAttend
-			rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Where("nickname = ?", m.Nickname).Find(&attends)
+			rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Where("mention = ?", m.Mention).Find(&attends)
 
 			var selectOptions []discordgo.SelectMenuOption
 			for _, a := range attends {
@@ -1016,14 +1201,13 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 			}
 
 			// delete attend
-			rdb.Delete(&attend)
+			content := cancelRaidAttend(s, attend)
 
 			// send message
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseUpdateMessage,
 				Data: &discordgo.InteractionResponseData{
-					Content: fmt.Sprintf("[%s] %s (%d 트라이) 참가 신청이 취소되었습니다.",
-						attend.RaidSchedule.Raid.RaidName, attend.RaidSchedule.StartTime.In(loc).Format("2006-01-02 15:04"), attend.RaidSchedule.TryCount),
+					Content: content,
 					Components: []discordgo.MessageComponent{
 						discordgo.ActionsRow{
 							Components: []discordgo.MessageComponent{
@@ -1062,8 +1246,16 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 				return
 			}
 
-			// delete attendee
+			// delete attendee, a confirmed member removed after the sign-up closed counts as a late cancel
 			rdb.Delete(&attend)
+			if !attend.Waitlisted && raidCancelOutcome(attend.RaidSchedule) == model.RaidLedgerCanceledLate {
+				recordRaidLedger(attend.RaidSchedule, attend.MemberInfo, model.RaidLedgerCanceledLate, true)
+			} else {
+				deleteRaidLedger(attend.RaidScheduleID, attend.Mention)
+			}
+			if !attend.Waitlisted {
+				promoteWaitlistedAttends(s, attend.RaidScheduleID)
+			}
 
 			// send message
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -1121,6 +1313,10 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 			// update attendee
 			attend.Canceled = true
 			rdb.Save(&attend)
+			recordRaidLedger(attend.RaidSchedule, attend.MemberInfo, model.RaidLedgerNotFormed, true)
+			if !attend.Waitlisted {
+				promoteWaitlistedAttends(s, attend.RaidScheduleID)
+			}
 
 			// send message
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -1221,12 +1417,12 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 
 			// get attend
 			var attends []model.RaidAttend
-			rdb.Preload("MemberInfo").Where("raid_schedule_id = ? AND canceled = ?", scheduleID, false).Find(&attends)
+			rdb.Preload("MemberInfo").Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", scheduleID, false, false).Find(&attends)
 			attendCount := len(attends)
 
 			// get info
 			var info model.RaidInfo
-			err = rdb.Where("raid_schedule_id = ?", scheduleID).First(&info).Error
+			err = rdb.Preload("Attendances").Where("raid_schedule_id = ?", scheduleID).First(&info).Error
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				// create message
 				msg, err := s.ChannelMessageSend(environment.DiscordGuildRaidInfoChannelID,
@@ -1261,19 +1457,24 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 		}
 
 		// send message
-		discord.SendNewRaidScheduleModal(s, i.Interaction, raidID)
+		tryCount := 1
+		if rt, ok := getRaidType(raid); ok {
+			tryCount = rt.DefaultTryCount
+		}
+		discord.SendNewRaidScheduleModal(s, i.Interaction, raidID, tryCount)
 	case "admin-remove-schedule-select-schedule":
 		scheduleID := args[1]
 
 		// get schedule
 		var schedule model.RaidSchedule
-		err := rdb.First(&schedule, scheduleID).Error
+		err := rdb.Preload("Raid").First(&schedule, scheduleID).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
 		}
 
 		// delete schedule
-		rdb.Delete(&schedule)
+		skipTemplateOccurrence(schedule)
+		removeRaidSchedule(s, schedule)
 
 		// send message
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -1299,7 +1500,11 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 		// create user list
 		var attendList []string
 		for _, a := range attends {
-			attendList = append(attendList, fmt.Sprintf("%s / %d / %s", a.MemberInfo.SubRoleName, a.Level, a.Nickname))
+			item := fmt.Sprintf("%s / %d / %s", a.MemberInfo.SubRoleName, a.Level, a.Nickname)
+			if a.Waitlisted {
+				item += " (대기)"
+			}
+			attendList = append(attendList, item)
 		}
 		attendListStr := strings.Join(attendList, "\n")
 
@@ -1496,20 +1701,25 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 
 		// get info
 		var info model.RaidInfo
-		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&info, infoID).Error
+		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Preload("Attendances").First(&info, infoID).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
 		}
 
 		// get attend
 		var attends []model.RaidAttend
-		rdb.Where("raid_schedule_id = ? AND canceled = ?", info.RaidScheduleID, false).Find(&attends)
+		rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
 		attendCount := len(attends)
 
 		// update info
 		info.EntranceTime = time.Now().UTC()
 		rdb.Save(&info)
 
+		// check attendance from raid voice channel
+		if err := snapshotRaidAttendance(s, &info, attends); err != nil {
+			fmt.Println("failed to snapshot raid attendance:", err)
+		}
+
 		// send message
 		discord.SendAdminRaidInfoResponse(s, i.Interaction, info.RaidSchedule, info, attendCount)
 	case "admin-info-record-start":
@@ -1517,14 +1727,14 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 
 		// get info
 		var info model.RaidInfo
-		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&info, infoID).Error
+		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Preload("Attendances").First(&info, infoID).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
 		}
 
 		// get attend
 		var attends []model.RaidAttend
-		rdb.Where("raid_schedule_id = ? AND canceled = ?", info.RaidScheduleID, false).Find(&attends)
+		rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
 		attendCount := len(attends)
 
 		// update info
@@ -1538,14 +1748,14 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 
 		// get info
 		var info model.RaidInfo
-		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&info, infoID).Error
+		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Preload("Attendances").First(&info, infoID).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
 		}
 
 		// get attend
 		var attends []model.RaidAttend
-		rdb.Where("raid_schedule_id = ? AND canceled = ?", info.RaidScheduleID, false).Find(&attends)
+		rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
 		attendCount := len(attends)
 
 		// update info
@@ -1554,26 +1764,149 @@ func raidScheduleIntegratedHandler(s *discordgo.Session, i *discordgo.Interactio
 
 		// send message
 		discord.SendAdminRaidInfoResponse(s, i.Interaction, info.RaidSchedule, info, attendCount)
+	case "raid-join":
+		raidSubscriptionButtonHandler(s, i, args[1], true)
+	case "raid-leave":
+		raidSubscriptionButtonHandler(s, i, args[1], false)
+	case "raid-join-pref":
+		raidAttendPreferenceHandler(s, i, args[1], args[2], args[3])
+	case "raid-join-char":
+		raidJoinCharacterHandler(s, i, args[1], i.MessageComponentData().Values[0])
+	case "admin-report":
+		raidReportHandler(s, i, args[1])
+	case "admin-manager-select-raid":
+		raidManagerHandler(s, i, args[1], "")
+	case "admin-manager-add":
+		discord.SendAddRaidManagerModal(s, i.Interaction, args[1])
+	case "admin-manager-remove":
+		raidManagerRemoveHandler(s, i, args[1])
+	case "admin-manager-claim":
+		raidManagerClaimHandler(s, i, args[1])
+	case "admin-stats":
+		raidLedgerStatsHandler(s, i, args[1])
+	case "admin-info-attendance":
+		raidAttendanceHandler(s, i, args[1], false)
+	case "admin-info-attendance-refresh":
+		raidAttendanceHandler(s, i, args[1], true)
 	case "admin-info-party-formation":
 		infoID := args[1]
+		raidPartyFormationHandler(s, i, infoID, "")
+	case "admin-template-add-select-raid":
+		// get raid
+		var raid model.Raid
+		err := rdb.First(&raid, args[1]).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return
+		}
+
+		tryCount := 1
+		if rt, ok := getRaidType(raid); ok {
+			tryCount = rt.DefaultTryCount
+		}
+		discord.SendRaidScheduleTemplateModal(s, i.Interaction, "반복 일정 추가", "add-raid-template-modal_"+args[1], "", "21:00", tryCount, 1, 0)
+	case "admin-template-edit":
+		raidTemplateEditModal(s, i, args[1])
+	case "admin-template-toggle":
+		raidTemplateToggleHandler(s, i, args[1])
+	case "admin-template-remove":
+		raidTemplateRemoveHandler(s, i, args[1])
+	case "admin-info-view":
+		infoID := args[1]
 
 		// get info
 		var info model.RaidInfo
-		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&info, infoID).Error
+		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Preload("Attendances").First(&info, infoID).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
 		}
 
 		// get attend
 		var attends []model.RaidAttend
-		rdb.Where("raid_schedule_id = ? AND canceled = ?", info.RaidScheduleID, false).Find(&attends)
+		rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
+		attendCount := len(attends)
+
+		// send message
+		discord.SendAdminRaidInfoResponse(s, i.Interaction, info.RaidSchedule, info, attendCount)
+	case "admin-party-select-party":
+		raidPartySelectHandler(s, i, args[1])
+	case "admin-party-set-members":
+		raidPartySetMembersHandler(s, i, args[1])
+	case "admin-party-edit-page":
+		if len(args) < 3 {
+			return
+		}
+		page, err := strconv.Atoi(args[2])
+		if err != nil {
+			return
+		}
+		raidPartyEditHandler(s, i, args[1], page)
+	case "admin-party-set-role":
+		raidPartySetRoleHandler(s, i, args[1])
+	case "admin-party-remove":
+		raidPartyRemoveHandler(s, i, args[1])
+	case "admin-party-publish":
+		raidPartyPublishHandler(s, i, args[1])
+	case "admin-info-distribution":
+		raidDistributionHandler(s, i, args[1], "")
+	case "admin-info-distribution-select-rule":
+		raidDistributionSelectRuleHandler(s, i, args[1])
+	case "admin-info-distribution-calc":
+		raidDistributionCalcHandler(s, i, args[1])
+	case "admin-distribution-add-rule":
+		discord.SendNewDistributionRuleModal(s, i.Interaction, args[1])
+	case "admin-info-loot":
+		infoID := args[1]
+
+		// get info
+		var info model.RaidInfo
+		err := rdb.First(&info, infoID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return
+		}
+
+		discord.SendRaidLootModal(s, i.Interaction, info)
+	case "admin-party-auto":
+		raidPartyAutoHandler(s, i, args[1])
+	case "admin-party-auto-apply":
+		raidPartyAutoApplyHandler(s, i, args, false)
+	case "admin-party-auto-confirm":
+		raidPartyAutoApplyHandler(s, i, args, true)
+	case "admin-party-draft-view":
+		raidPartyDraftViewHandler(s, i, args)
+	case "admin-party-draft-pick":
+		raidPartyDraftPickHandler(s, i, args)
+	case "admin-party-draft-move":
+		raidPartyDraftMoveHandler(s, i, args)
 	}
 }
 
 func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCreate, modalID string) {
 	modalIdSplit := strings.Split(modalID, "_")
+	if !authorizeRaidAction(s, i, modalIdSplit) {
+		return
+	}
 
 	switch modalIdSplit[0] {
+	case "raid-join-modal":
+		raidAttendModalHandler(s, i, modalIdSplit[1], modalIdSplit[2], modalIdSplit[3])
+	case "add-raid-template-modal":
+		// get raid
+		var raid model.Raid
+		err := rdb.First(&raid, modalIdSplit[1]).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return
+		}
+
+		raidTemplateModalHandler(s, i, model.RaidScheduleTemplate{RaidID: raid.ID})
+	case "edit-raid-template-modal":
+		// get template
+		var tpl model.RaidScheduleTemplate
+		err := rdb.First(&tpl, modalIdSplit[1]).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return
+		}
+
+		raidTemplateModalHandler(s, i, tpl)
 	case "add-raid-modal":
 		modalData := i.ModalSubmitData().Components
 
@@ -1594,6 +1927,29 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 			}
 		}
 
+		// check raid type
+		raidType = strings.TrimSpace(raidType)
+		if _, ok := raidTypeList[raidType]; !ok {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("레이드 타입 '%s'를 찾을 수 없습니다. (%s)", raidType, strings.Join(raidTypeOrder, "/")),
+					Components: []discordgo.MessageComponent{
+						discordgo.ActionsRow{
+							Components: []discordgo.MessageComponent{
+								discordgo.Button{
+									Label:    "다시 입력하기",
+									Style:    discordgo.PrimaryButton,
+									CustomID: "admin-add-new-raid",
+								},
+							},
+						},
+					},
+				},
+			})
+			return
+		}
+
 		// find existing raid with raidName
 		var raid model.Raid
 		err := rdb.Where("raid_name = ?", raidName).First(&raid).Error
@@ -1606,6 +1962,7 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 			RaidName:    raidName,
 			Type:        raidType,
 			Description: raidDescription,
+			Manager:     fmt.Sprintf("<@%s>", interactionUserID(i)),
 		}
 		rdb.Create(&newRaid)
 
@@ -1629,7 +1986,7 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 		})
 	case "add-raid-schedule-modal":
 		modalData := i.ModalSubmitData().Components
-		var startTime, subscriptionEndTime, tryCount string
+		var startTime, subscriptionEndTime, tryCount, capacity, jobCapacity string
 		for _, comp := range modalData {
 			if ar, ok := comp.(*discordgo.ActionsRow); ok {
 				if ti, ok := ar.Components[0].(*discordgo.TextInput); ok {
@@ -1642,6 +1999,12 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 					if ti.CustomID == "subscription-end-time" {
 						subscriptionEndTime = ti.Value
 					}
+					if ti.CustomID == "capacity" {
+						capacity = ti.Value
+					}
+					if ti.CustomID == "job-capacity" {
+						jobCapacity = ti.Value
+					}
 				}
 			}
 		}
@@ -1669,11 +2032,13 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 		if err != nil {
 			return
 		}
-
-		// create member attend message in channel
-		m, err := s.ChannelMessageSend(environment.DiscordGuildRaidSubscriptionChannelID, fmt.Sprintf("**%s - %d트라이 (%s 출발)**",
-			t.Format("01월 02일"), raidCountInt, t.Format("15:04")))
-		if err != nil {
+		capacityInt, err := strconv.Atoi(strings.TrimSpace(capacity))
+		if err != nil || capacityInt < 0 {
+			respondEphemeral(s, i, fmt.Sprintf("정원 '%s'이(가) 올바르지 않습니다. 0 이상의 숫자를 입력해주세요. (0은 제한 없음)", capacity))
+			return
+		}
+		if _, err := parseJobCapacity(jobCapacity); err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("직업별 정원 '%s'이(가) 올바르지 않습니다. (%s)\n'비숍:2,전사:6'처럼 직업 또는 계열과 인원을 입력해주세요.", jobCapacity, err))
 			return
 		}
 
@@ -1682,22 +2047,31 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 			TryCount:            raidCountInt,
 			StartTime:           t.UTC(),
 			SubscriptionEndTime: te.UTC(),
-			MessageID:           m.ID,
+			Capacity:            capacityInt,
+			JobCapacity:         strings.TrimSpace(jobCapacity),
+		}
+		if err := createRaidSchedule(s, &newRaidSchedule); err != nil {
+			fmt.Println("failed to create raid schedule:", err)
+			return
 		}
 
-		rdb.Create(&newRaidSchedule)
+		content := "레이드 일정이 추가되었습니다."
+		newRaidSchedule.Raid = raid
+		if overlaps := findOverlappingSchedules(newRaidSchedule); len(overlaps) > 0 {
+			content += "\n\n(주의: 아래 일정과 시간이 겹칩니다.)\n" + describeRaidScheduleConflicts(overlaps)
+		}
 
 		// send message
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("레이드 일정이 추가되었습니다."),
+				Content: content,
 			},
 		})
 	case "edit-raid-schedule-modal":
 		modalData := i.ModalSubmitData().Components
 
-		var startTime, sTime, tryCount string
+		var startTime, sTime, tryCount, capacity, jobCapacity string
 		for _, comp := range modalData {
 			if ar, ok := comp.(*discordgo.ActionsRow); ok {
 				if ti, ok := ar.Components[0].(*discordgo.TextInput); ok {
@@ -1710,6 +2084,12 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 					if ti.CustomID == "subscription-end-time" {
 						sTime = ti.Value
 					}
+					if ti.CustomID == "capacity" {
+						capacity = ti.Value
+					}
+					if ti.CustomID == "job-capacity" {
+						jobCapacity = ti.Value
+					}
 				}
 			}
 		}
@@ -1719,7 +2099,7 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 
 		// get schedule
 		var schedule model.RaidSchedule
-		err := rdb.First(&schedule, scheduleID).Error
+		err := rdb.Preload("Raid").First(&schedule, scheduleID).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
 		}
@@ -1740,16 +2120,43 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 			return
 		}
 
+		capacityInt, err := strconv.Atoi(strings.TrimSpace(capacity))
+		if err != nil || capacityInt < 0 {
+			respondEphemeral(s, i, fmt.Sprintf("정원 '%s'이(가) 올바르지 않습니다. 0 이상의 숫자를 입력해주세요. (0은 제한 없음)", capacity))
+			return
+		}
+		if _, err := parseJobCapacity(jobCapacity); err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("직업별 정원 '%s'이(가) 올바르지 않습니다. (%s)\n'비숍:2,전사:6'처럼 직업 또는 계열과 인원을 입력해주세요.", jobCapacity, err))
+			return
+		}
+
+		before := schedule
 		schedule.TryCount = raidCountInt
 		schedule.StartTime = t.UTC()
 		schedule.SubscriptionEndTime = ts.UTC()
+		schedule.Capacity = capacityInt
+		schedule.JobCapacity = strings.TrimSpace(jobCapacity)
 		rdb.Save(&schedule)
+		if !before.StartTime.Equal(schedule.StartTime) {
+			// the template would otherwise fill the original slot again
+			skipTemplateOccurrence(before)
+		}
+
+		// capacity may have grown
+		promoteWaitlistedAttends(s, schedule.ID)
+		resetRaidReminders(before, schedule)
+		notifyRaidScheduleChange(s, before, schedule)
+
+		content := "레이드 일정이 수정되었습니다."
+		if overlaps := findOverlappingSchedules(schedule); len(overlaps) > 0 {
+			content += "\n\n(주의: 아래 일정과 시간이 겹칩니다.)\n" + describeRaidScheduleConflicts(overlaps)
+		}
 
 		// send message
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("레이드 일정이 수정되었습니다."),
+				Content: content,
 				Components: []discordgo.MessageComponent{
 					discordgo.ActionsRow{
 						Components: []discordgo.MessageComponent{
@@ -1763,6 +2170,12 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 				},
 			},
 		})
+	case "add-raid-manager-modal":
+		raidManagerModalHandler(s, i, modalIdSplit[1])
+	case "add-distribution-rule-modal":
+		raidDistributionRuleModalHandler(s, i, modalIdSplit[1])
+	case "raid-loot-modal":
+		raidLootModalHandler(s, i, modalIdSplit[1])
 	case "admin-add-attendee-modal":
 		modalData := i.ModalSubmitData().Components
 
@@ -1780,39 +2193,43 @@ func raidScheduleModalHandler(s *discordgo.Session, i *discordgo.InteractionCrea
 		// get scheduleID
 		scheduleID := modalIdSplit[1]
 
-		// get user
-		memberMap := cache.ListAllMembersNicknameMap()
-		if _, ok := memberMap[nickname]; !ok {
-			return
-		}
-
-		// get member
-		m, err := GetMemberInfoFromMember(memberMap[nickname])
+		// get member, registered characters included
+		m, err := findMemberInfoByNickname(nickname)
 		if err != nil {
 			return
 		}
 
 		// check schedule is valid
 		var schedule model.RaidSchedule
-		err = rdb.First(&schedule, scheduleID).Error
+		err = rdb.Preload("Raid").First(&schedule, scheduleID).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return
 		}
 
+		content := fmt.Sprintf("참가자 '%s'가 추가되었습니다.", nickname)
+		if rt, ok := getRaidType(schedule.Raid); ok && m.Level < rt.MinLevel {
+			content += fmt.Sprintf("\n(주의: 최소 레벨 %d 미만인 참가자입니다. 현재 레벨 %d)", rt.MinLevel, m.Level)
+		}
+		if conflicts := findMemberConflicts(schedule, m.Mention); len(conflicts) > 0 {
+			content += "\n(주의: 이미 참가중인 아래 일정과 시간이 겹칩니다.)\n" + describeRaidScheduleConflicts(conflicts)
+		}
+
 		// create attend
 		newAttend := model.RaidAttend{
 			MemberInfo:     *m,
 			RaidScheduleID: schedule.ID,
 			Canceled:       false,
+			Preference:     model.RaidAttendPreferenceMain,
 		}
 
 		rdb.Create(&newAttend)
+		recordRaidLedger(schedule, *m, model.RaidLedgerSignedUp, true)
 
 		// send message
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("참가자 '%s'가 추가되었습니다.", nickname),
+				Content: content,
 				Components: []discordgo.MessageComponent{
 					discordgo.ActionsRow{
 						Components: []discordgo.MessageComponent{
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"sort"
+)
+
+// listRaidVoiceChannelUsers returns the ids of users currently in the raid voice channel.
+func listRaidVoiceChannelUsers(s *discordgo.Session) (map[string]bool, error) {
+	s.State.RLock()
+	defer s.State.RUnlock()
+
+	g, err := s.State.Guild(environment.DiscordGuildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild state: %w", err)
+	}
+
+	users := make(map[string]bool)
+	for _, vs := range g.VoiceStates {
+		if vs.ChannelID == environment.DiscordGuildRaidVoiceChannelID {
+			users[vs.UserID] = true
+		}
+	}
+	return users, nil
+}
+
+// snapshotRaidAttendance compares the raid voice channel against the signed-up attendees
+// and records present, absent and walk-in members on the raid info.
+func snapshotRaidAttendance(s *discordgo.Session, info *model.RaidInfo, attends []model.RaidAttend) error {
+	users, err := listRaidVoiceChannelUsers(s)
+	if err != nil {
+		return err
+	}
+
+	var attendances []model.RaidAttendance
+	signedUp := make(map[string]bool)
+	for _, a := range attends {
+		userID := userIDFromMention(a.Mention)
+		signedUp[userID] = true
+
+		status := model.RaidAttendanceAbsent
+		if users[userID] {
+			status = model.RaidAttendancePresent
+		}
+		attendances = append(attendances, model.RaidAttendance{RaidInfoID: info.ID, MemberInfo: a.MemberInfo, Status: status})
+	}
+
+	for userID := range users {
+		if signedUp[userID] {
+			continue
+		}
+
+		m := cache.GetGuildMember(userID)
+		if m == nil {
+			// not a guild gamer, e.g. bots or guests
+			continue
+		}
+		mi, err := GetMemberInfoFromMember(m)
+		if err != nil {
+			mi = &model.MemberInfo{Nickname: m.Nick}
+		}
+		mi.Mention = m.Mention()
+		attendances = append(attendances, model.RaidAttendance{RaidInfoID: info.ID, MemberInfo: *mi, Status: model.RaidAttendanceWalkIn})
+	}
+
+	// replace previous snapshot
+	rdb.Where("raid_info_id = ?", info.ID).Delete(&model.RaidAttendance{})
+	if len(attendances) > 0 {
+		if err := rdb.Create(&attendances).Error; err != nil {
+			return fmt.Errorf("failed to save raid attendance: %w", err)
+		}
+	}
+	info.Attendances = attendances
+	recordRaidLedgerAttendance(info.RaidSchedule, attendances)
+
+	return updateRaidInfoMessage(s, *info)
+}
+
+func renderRaidAttendances(attendances []model.RaidAttendance, mention bool) string {
+	var statuses = []string{model.RaidAttendancePresent, model.RaidAttendanceAbsent, model.RaidAttendanceWalkIn}
+
+	sorted := make([]model.RaidAttendance, len(attendances))
+	copy(sorted, attendances)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Nickname < sorted[j].Nickname
+	})
+
+	msg := ""
+	for _, status := range statuses {
+		var lines []string
+		for _, a := range sorted {
+			if a.Status != status {
+				continue
+			}
+			name := a.Nickname
+			if mention && a.Mention != "" {
+				name = a.Mention
+			}
+			lines = append(lines, fmt.Sprintf("* %s / %d / %s", a.SubRoleName, a.Level, name))
+		}
+		msg += fmt.Sprintf("**%s** (%d명)\n", status, len(lines))
+		for _, l := range lines {
+			msg += l + "\n"
+		}
+	}
+	return msg
+}
+
+func raidAttendanceHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string, refresh bool) {
+	// get info
+	var info model.RaidInfo
+	err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Preload("Attendances").First(&info, infoID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	msg := ""
+	if refresh {
+		// get attend
+		var attends []model.RaidAttend
+		rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
+
+		if err := snapshotRaidAttendance(s, &info, attends); err != nil {
+			fmt.Println("failed to snapshot raid attendance:", err)
+			msg += "음성 채널 출석 확인에 실패했습니다.\n\n"
+		}
+	}
+
+	msg += fmt.Sprintf("**[%s] %s (%d트라이) 출석 체크**\n\n",
+		info.RaidSchedule.Raid.RaidName, info.RaidSchedule.StartTime.In(loc).Format("2006-01-02 15:04"), info.RaidSchedule.TryCount)
+	if len(info.Attendances) == 0 {
+		msg += "출석 기록이 없습니다. 입장 기록 시 레이드 음성 채널 인원이 자동으로 기록됩니다.\n"
+	} else {
+		msg += renderRaidAttendances(info.Attendances, false)
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "지금 다시 확인",
+							Style:    discordgo.PrimaryButton,
+							CustomID: fmt.Sprintf("admin-info-attendance-refresh_%d", info.ID),
+						},
+						discordgo.Button{
+							Label:    "레이드 기록으로 돌아가기",
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("admin-info-view_%d", info.ID),
+						},
+					},
+				},
+			},
+		},
+	})
+}
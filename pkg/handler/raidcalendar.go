@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/calendar"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var raidCalendarMemberPath = regexp.MustCompile(`^/raids/([0-9a-f]{32})\.ics$`)
+
+var raidCalendarServer *http.Server
+
+// listUpcomingRaidSchedules returns upcoming schedules, only the ones the member signed up for if mention is given.
+func listUpcomingRaidSchedules(mention string) []model.RaidSchedule {
+	query := rdb.Preload("Raid").Where("start_time > ?", time.Now().UTC())
+	if mention != "" {
+		query = query.Where("id IN (?)", rdb.Model(&model.RaidAttend{}).Select("raid_schedule_id").
+			Where("mention = ? AND canceled = ?", mention, false))
+	}
+
+	var schedules []model.RaidSchedule
+	query.Order("start_time").Find(&schedules)
+	return schedules
+}
+
+func renderRaidCalendar(name string, schedules []model.RaidSchedule) []byte {
+	var events []calendar.Event
+	for _, sc := range schedules {
+		description := fmt.Sprintf("신청 마감: %s", sc.SubscriptionEndTime.In(loc).Format("2006-01-02 15:04"))
+		if sc.Raid.Description != "" {
+			description = sc.Raid.Description + "\n" + description
+		}
+
+		events = append(events, calendar.Event{
+			UID:         fmt.Sprintf("raid-schedule-%d@eternity-bot", sc.ID),
+			Summary:     fmt.Sprintf("[%s] %d트라이", sc.Raid.RaidName, sc.TryCount),
+			Description: description,
+			Start:       sc.StartTime,
+			End:         sc.StartTime.Add(raidDuration(sc.Raid)),
+		})
+	}
+	return calendar.Render(name, events)
+}
+
+// getRaidCalendarToken returns the member's feed token, issuing one on first use.
+func getRaidCalendarToken(userID string) (string, error) {
+	var token model.RaidCalendarToken
+	result := rdb.Where("discord_user_id = ?", userID).Limit(1).Find(&token)
+	if result.Error != nil {
+		return "", fmt.Errorf("failed to get calendar token: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return token.Token, nil
+	}
+	return rotateRaidCalendarToken(userID)
+}
+
+// rotateRaidCalendarToken issues a new feed token to the member, the previous feed address stops working.
+func rotateRaidCalendarToken(userID string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+
+	var token model.RaidCalendarToken
+	rdb.Where("discord_user_id = ?", userID).Limit(1).Find(&token)
+	token.DiscordUserID = userID
+	token.Token = hex.EncodeToString(buf)
+	if err := rdb.Save(&token).Error; err != nil {
+		return "", fmt.Errorf("failed to save calendar token: %w", err)
+	}
+	return token.Token, nil
+}
+
+// RaidCalendarServe starts serving the raid schedules as iCalendar feeds in the background until RaidCalendarShutdown is called.
+// /raids.ics has every upcoming schedule and /raids/<token>.ics has the schedules of the member the token was issued to.
+func RaidCalendarServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/raids.ics", func(w http.ResponseWriter, r *http.Request) {
+		writeRaidCalendar(w, renderRaidCalendar("영원길드 레이드", listUpcomingRaidSchedules("")))
+	})
+	mux.HandleFunc("/raids/", func(w http.ResponseWriter, r *http.Request) {
+		match := raidCalendarMemberPath.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			http.NotFound(w, r)
+			return
+		}
+		var token model.RaidCalendarToken
+		if rdb.Where("token = ?", match[1]).Limit(1).Find(&token).RowsAffected == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		mention := fmt.Sprintf("<@%s>", token.DiscordUserID)
+		writeRaidCalendar(w, renderRaidCalendar("영원길드 내 레이드", listUpcomingRaidSchedules(mention)))
+	})
+
+	raidCalendarServer = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	go func() {
+		if err := raidCalendarServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("failed to serve raid calendar:", err)
+		}
+	}()
+	return nil
+}
+
+func RaidCalendarShutdown(ctx context.Context) {
+	if raidCalendarServer == nil {
+		return
+	}
+	if err := raidCalendarServer.Shutdown(ctx); err != nil {
+		fmt.Println("failed to shut down raid calendar server:", err)
+	}
+}
+
+func writeRaidCalendar(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	_, _ = w.Write(body)
+}
+
+func raidCalendarHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	memberInfo := cache.GetGuildMember(i.User.ID)
+	if memberInfo == nil {
+		return
+	}
+	m, err := GetMemberInfoFromMember(memberInfo)
+	if err != nil {
+		return
+	}
+
+	msg := "레이드 일정 캘린더 파일입니다. 파일을 열어 휴대폰 캘린더에 추가할 수 있습니다.\n"
+	var components []discordgo.MessageComponent
+	if raidCalendarSubscribable() {
+		token, err := getRaidCalendarToken(i.User.ID)
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			msg += "\n" + describeRaidCalendarFeeds(token)
+			components = raidCalendarComponents()
+		}
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: components,
+			Files: []*discordgo.File{
+				{
+					Name:        "eternity-raids.ics",
+					ContentType: "text/calendar",
+					Reader:      bytes.NewReader(renderRaidCalendar("영원길드 레이드", listUpcomingRaidSchedules(""))),
+				},
+				{
+					Name:        "eternity-my-raids.ics",
+					ContentType: "text/calendar",
+					Reader:      bytes.NewReader(renderRaidCalendar("영원길드 내 레이드", listUpcomingRaidSchedules(m.Mention))),
+				},
+			},
+		},
+	})
+}
+
+func raidCalendarSubscribable() bool {
+	return environment.RaidCalendarHTTPAddr != "" && environment.RaidCalendarBaseURL != ""
+}
+
+func describeRaidCalendarFeeds(token string) string {
+	baseURL := strings.TrimSuffix(environment.RaidCalendarBaseURL, "/")
+	msg := "캘린더 앱에서 아래 주소를 구독하면 일정이 자동으로 갱신됩니다. 내 일정 주소는 다른 사람과 공유하지 마세요.\n"
+	msg += fmt.Sprintf("* 전체 일정: <%s/raids.ics>\n", baseURL)
+	msg += fmt.Sprintf("* 내 일정: <%s/raids/%s.ics>\n", baseURL, token)
+	return msg
+}
+
+func raidCalendarComponents() []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "내 일정 주소 재발급",
+					Style:    discordgo.DangerButton,
+					CustomID: "user-calendar-rotate",
+				},
+			},
+		},
+	}
+}
+
+// raidCalendarRotateHandler issues a new feed address, for members whose address has leaked.
+func raidCalendarRotateHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !raidCalendarSubscribable() {
+		return
+	}
+
+	token, err := rotateRaidCalendarToken(i.User.ID)
+	if err != nil {
+		fmt.Println(err)
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "주소를 재발급하지 못했습니다. 잠시 후 다시 시도해주세요.",
+			},
+		})
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    "내 일정 주소를 재발급했습니다. 이전 주소는 더 이상 동작하지 않으니 캘린더 앱에서 새 주소로 다시 구독해주세요.\n\n" + describeRaidCalendarFeeds(token),
+			Components: raidCalendarComponents(),
+		},
+	})
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+)
+
+// calendarEvents splits the rendered calendar into the content lines of each VEVENT.
+func calendarEvents(ics string) [][]string {
+	var events [][]string
+	var current []string
+	for _, line := range strings.Split(ics, "\r\n") {
+		switch {
+		case line == "BEGIN:VEVENT":
+			current = []string{}
+		case line == "END:VEVENT":
+			events = append(events, current)
+			current = nil
+		case current != nil:
+			current = append(current, line)
+		}
+	}
+	return events
+}
+
+func TestRenderRaidCalendar(t *testing.T) {
+	start := time.Date(2026, time.October, 21, 12, 0, 0, 0, time.UTC)
+	events := calendarEvents(string(renderRaidCalendar("레이드", []model.RaidSchedule{
+		{
+			Model:               gorm.Model{ID: 7},
+			Raid:                model.Raid{RaidName: "주간 혼테일", Type: "혼테일"},
+			TryCount:            1,
+			StartTime:           start,
+			SubscriptionEndTime: start.Add(-3 * time.Hour),
+		},
+		{
+			Model:               gorm.Model{ID: 8},
+			Raid:                model.Raid{RaidName: "친목", Description: "자유 참여"},
+			TryCount:            2,
+			StartTime:           start,
+			SubscriptionEndTime: start,
+		},
+	})))
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	known := strings.Join(events[0], "\n")
+	for _, want := range []string{
+		"UID:raid-schedule-7@eternity-bot",
+		"SUMMARY:[주간 혼테일] 1트라이",
+		"DTSTART;TZID=Asia/Seoul:20261021T210000",
+		// 혼테일 takes two hours
+		"DTEND;TZID=Asia/Seoul:20261021T230000",
+		"DESCRIPTION:신청 마감: 2026-10-21 18:00",
+	} {
+		if !strings.Contains(known, want) {
+			t.Errorf("first event has no %q:\n%s", want, known)
+		}
+	}
+
+	// without a known type the event lasts the default hour and the raid description comes first
+	other := strings.Join(events[1], "\n")
+	for _, want := range []string{
+		"SUMMARY:[친목] 2트라이",
+		"DTEND;TZID=Asia/Seoul:20261021T220000",
+		`DESCRIPTION:자유 참여\n신청 마감: 2026-10-21 21:00`,
+	} {
+		if !strings.Contains(other, want) {
+			t.Errorf("second event has no %q:\n%s", want, other)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// parseJobCapacity parses per-job caps such as "비숍:2,전사:4".
+// The job can be either a sub role (비숍) or a main role (전사).
+func parseJobCapacity(text string) (map[string]int, error) {
+	caps := map[string]int{}
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid job capacity: %s", item)
+		}
+		job := strings.TrimSpace(kv[0])
+		if _, ok := mainRoleList[job]; !ok && !isSubRole(job) {
+			return nil, fmt.Errorf("unknown job: %s", job)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid job capacity: %s", item)
+		}
+		caps[job] = n
+	}
+	return caps, nil
+}
+
+func isSubRole(job string) bool {
+	for _, srs := range mainRoleList {
+		for _, sr := range srs {
+			if sr == job {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesJob(m model.MemberInfo, job string) bool {
+	return m.SubRoleName == job || m.MainRoleName == job
+}
+
+func countAttendsByJob(attends []model.RaidAttend, job string) int {
+	count := 0
+	for _, a := range attends {
+		if matchesJob(a.MemberInfo, job) {
+			count++
+		}
+	}
+	return count
+}
+
+// sign-ups and waitlist promotions of a schedule are serialized,
+// so concurrent clicks cannot both take the last slot or sign the same member up twice
+var raidScheduleLocks = make(map[uint]*sync.Mutex)
+var raidScheduleLocksLock = &sync.Mutex{}
+
+// lockRaidSchedule locks the sign-ups of the schedule and returns the unlock function.
+func lockRaidSchedule(scheduleID uint) func() {
+	raidScheduleLocksLock.Lock()
+	l, ok := raidScheduleLocks[scheduleID]
+	if !ok {
+		l = &sync.Mutex{}
+		raidScheduleLocks[scheduleID] = l
+	}
+	raidScheduleLocksLock.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// countedAttends returns the attends that take a slot, backups do not.
+func countedAttends(attends []model.RaidAttend) []model.RaidAttend {
+	var counted []model.RaidAttend
+	for _, a := range attends {
+		if attendPreference(a) != model.RaidAttendPreferenceBackup {
+			counted = append(counted, a)
+		}
+	}
+	return counted
+}
+
+// canJoinRaidSchedule reports whether the attend fits into the schedule's capacity and job caps.
+// Backups never take a slot, so they always fit and never push a main member onto the waitlist.
+func canJoinRaidSchedule(schedule model.RaidSchedule, attend model.RaidAttend, attends []model.RaidAttend) bool {
+	if attendPreference(attend) == model.RaidAttendPreferenceBackup {
+		return true
+	}
+
+	attends = countedAttends(attends)
+	if schedule.Capacity > 0 && len(attends) >= schedule.Capacity {
+		return false
+	}
+
+	caps, err := parseJobCapacity(schedule.JobCapacity)
+	if err != nil {
+		fmt.Println("failed to parse job capacity:", err)
+		return true
+	}
+	for job, n := range caps {
+		if matchesJob(attend.MemberInfo, job) && countAttendsByJob(attends, job) >= n {
+			return false
+		}
+	}
+	return true
+}
+
+func listWaitlistedAttends(scheduleID uint) []model.RaidAttend {
+	var waitlist []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", scheduleID, false, true).Find(&waitlist)
+	sort.Slice(waitlist, func(i, j int) bool {
+		return waitlist[i].ID < waitlist[j].ID
+	})
+	return waitlist
+}
+
+func waitlistPosition(attend model.RaidAttend) int {
+	for idx, w := range listWaitlistedAttends(attend.RaidScheduleID) {
+		if w.ID == attend.ID {
+			return idx + 1
+		}
+	}
+	return 0
+}
+
+// promoteWaitlistedAttends moves waitlisted members into the schedule in sign-up order
+// as long as they fit, and notifies each promoted member by DM.
+func promoteWaitlistedAttends(s *discordgo.Session, scheduleID uint) {
+	var schedule model.RaidSchedule
+	if err := rdb.Preload("Raid").First(&schedule, scheduleID).Error; err != nil {
+		return
+	}
+
+	unlock := lockRaidSchedule(schedule.ID)
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", schedule.ID, false, false).Find(&attends)
+
+	var promoted []model.RaidAttend
+	for _, w := range listWaitlistedAttends(schedule.ID) {
+		if !canJoinRaidSchedule(schedule, w, attends) {
+			continue
+		}
+
+		w.Waitlisted = false
+		rdb.Save(&w)
+		attends = append(attends, w)
+		promoted = append(promoted, w)
+	}
+	unlock()
+
+	for _, w := range promoted {
+		sendMessage(s, userIDFromMention(w.Mention), fmt.Sprintf("[%s] %s (%d 트라이) 대기 중이던 참가 신청이 확정되었습니다.",
+			schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount))
+	}
+}
+
+func userIDFromMention(mention string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(mention, "<@"), ">")
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestParseJobCapacity(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    map[string]int
+		wantErr bool
+	}{
+		{name: "empty", text: "", want: map[string]int{}},
+		{name: "sub and main roles", text: "비숍:2,전사:4", want: map[string]int{"비숍": 2, "전사": 4}},
+		{name: "spaces and trailing comma", text: " 비숍 : 1 , 궁수:0 ,", want: map[string]int{"비숍": 1, "궁수": 0}},
+		{name: "missing count", text: "비숍", wantErr: true},
+		{name: "unknown job", text: "도둑:1", wantErr: true},
+		{name: "negative count", text: "비숍:-1", wantErr: true},
+		{name: "not a number", text: "비숍:두명", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseJobCapacity(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinRaidScheduleConcurrently(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "raid.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.Raid{}, &model.RaidSchedule{}, &model.RaidAttend{}, &model.RaidAttendLedger{}); err != nil {
+		t.Fatal(err)
+	}
+	prev := rdb
+	rdb = db
+	t.Cleanup(func() { rdb = prev })
+
+	now := time.Now()
+	schedule := model.RaidSchedule{
+		Raid:                model.Raid{RaidName: "자쿰"},
+		StartTime:           now.Add(24 * time.Hour),
+		SubscriptionEndTime: now.Add(time.Hour),
+		Capacity:            3,
+	}
+	if err := db.Create(&schedule).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// every member tries at once; only the capacity may get in
+	var wg sync.WaitGroup
+	for n := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := model.MemberInfo{MainRoleName: "전사", SubRoleName: "히어로", Level: 100,
+				Nickname: fmt.Sprintf("멤버%d", n), Mention: fmt.Sprintf("<@%d>", n)}
+			if content, ok := joinRaidSchedule(schedule, m, model.RaidAttendPreferenceMain, ""); !ok {
+				t.Errorf("join %d: %s", n, content)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var joined, waitlisted int64
+	db.Model(&model.RaidAttend{}).Where("raid_schedule_id = ? AND waitlisted = ?", schedule.ID, false).Count(&joined)
+	db.Model(&model.RaidAttend{}).Where("raid_schedule_id = ? AND waitlisted = ?", schedule.ID, true).Count(&waitlisted)
+	if joined != 3 || waitlisted != 7 {
+		t.Errorf("got %d joined and %d waitlisted, want 3 and 7", joined, waitlisted)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"time"
+)
+
+// maxRaidDuration bounds the lookup window of overlapping schedules.
+const maxRaidDuration = 24 * time.Hour
+
+func raidScheduleEnd(sc model.RaidSchedule) time.Time {
+	return sc.StartTime.Add(raidDuration(sc.Raid))
+}
+
+func raidSchedulesOverlap(a, b model.RaidSchedule) bool {
+	return a.StartTime.Before(raidScheduleEnd(b)) && b.StartTime.Before(raidScheduleEnd(a))
+}
+
+// findOverlappingSchedules returns the other schedules whose expected time range overlaps with the schedule.
+// The schedule's Raid must be loaded.
+func findOverlappingSchedules(schedule model.RaidSchedule) []model.RaidSchedule {
+	var candidates []model.RaidSchedule
+	rdb.Preload("Raid").
+		Where("start_time > ? AND start_time < ? AND id <> ?",
+			schedule.StartTime.Add(-maxRaidDuration).UTC(), raidScheduleEnd(schedule).UTC(), schedule.ID).
+		Find(&candidates)
+
+	var overlaps []model.RaidSchedule
+	for _, c := range candidates {
+		if raidSchedulesOverlap(schedule, c) {
+			overlaps = append(overlaps, c)
+		}
+	}
+	return overlaps
+}
+
+// findMemberConflicts returns the schedules the member already joined that overlap with the schedule.
+func findMemberConflicts(schedule model.RaidSchedule, mention string) []model.RaidSchedule {
+	var conflicts []model.RaidSchedule
+	for _, sc := range findOverlappingSchedules(schedule) {
+		var count int64
+		rdb.Model(&model.RaidAttend{}).Where("raid_schedule_id = ? AND mention = ? AND canceled = ?", sc.ID, mention, false).Count(&count)
+		if count > 0 {
+			conflicts = append(conflicts, sc)
+		}
+	}
+	return conflicts
+}
+
+func describeRaidScheduleConflicts(schedules []model.RaidSchedule) string {
+	msg := ""
+	for _, sc := range schedules {
+		msg += fmt.Sprintf("* [%s] %s ~ %s (%d트라이)\n", sc.Raid.RaidName,
+			sc.StartTime.In(loc).Format("2006-01-02 15:04"), raidScheduleEnd(sc).In(loc).Format("15:04"), sc.TryCount)
+	}
+	return msg
+}
@@ -0,0 +1,414 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	distributionTypeEqual     = "균등"
+	distributionTypeRoleRatio = "역할비율"
+	distributionTypeFixed     = "고정"
+)
+
+var distributionTypeList = []string{distributionTypeEqual, distributionTypeRoleRatio, distributionTypeFixed}
+
+type payoutTarget struct {
+	model.MemberInfo
+	PartyRole string
+}
+
+// matches reports whether the target falls under the rule by party role and level.
+// TargetAttackPower is ignored since attack power is not tracked anywhere.
+func (t payoutTarget) matches(rule model.DistributionRule) bool {
+	if rule.TargetPartyRole != "" && t.PartyRole != rule.TargetPartyRole {
+		return false
+	}
+	return t.Level >= rule.TargetLevel
+}
+
+// fixedDistributionAmount returns the mesos of a 고정 rule, rules saved before FixedAmount existed keep them in Amount.
+func fixedDistributionAmount(rule model.DistributionRule) int64 {
+	if rule.FixedAmount > 0 {
+		return rule.FixedAmount
+	}
+	return int64(rule.Amount)
+}
+
+// calculateRaidPayouts splits the total loot among the targets by the given rule.
+// 균등 splits equally, 역할비율 weights matching members by rule.Amount and
+// 고정 pays rule.FixedAmount to matching members and splits the rest equally among the others.
+func calculateRaidPayouts(rule model.DistributionRule, total int64, targets []payoutTarget) []model.RaidPayout {
+	if len(targets) == 0 || total <= 0 {
+		return nil
+	}
+
+	amounts := make([]int64, len(targets))
+	switch rule.DistributionType {
+	case distributionTypeRoleRatio:
+		weights := make([]float64, len(targets))
+		sum := 0.0
+		for idx, t := range targets {
+			weights[idx] = 1
+			if t.matches(rule) {
+				weights[idx] = float64(rule.Amount)
+			}
+			sum += weights[idx]
+		}
+		if sum > 0 {
+			for idx := range targets {
+				amounts[idx] = int64(float64(total) * weights[idx] / sum)
+			}
+		}
+	case distributionTypeFixed:
+		remaining := total
+		var others []int
+		for idx, t := range targets {
+			if !t.matches(rule) {
+				others = append(others, idx)
+				continue
+			}
+			amounts[idx] = min(fixedDistributionAmount(rule), remaining)
+			remaining -= amounts[idx]
+		}
+		if len(others) > 0 {
+			for _, idx := range others {
+				amounts[idx] = remaining / int64(len(others))
+			}
+		}
+	default:
+		for idx := range targets {
+			amounts[idx] = total / int64(len(targets))
+		}
+	}
+
+	var payouts []model.RaidPayout
+	for idx, t := range targets {
+		payouts = append(payouts, model.RaidPayout{
+			MemberInfo: t.MemberInfo,
+			PartyRole:  t.PartyRole,
+			Amount:     amounts[idx],
+		})
+	}
+	return payouts
+}
+
+// listPayoutTargets returns party members if parties are formed, otherwise non-canceled attendees.
+func listPayoutTargets(info model.RaidInfo) []payoutTarget {
+	var targets []payoutTarget
+	parties := listRaidParties(info.ID)
+	for _, p := range parties {
+		for _, m := range p.Members {
+			targets = append(targets, payoutTarget{MemberInfo: m.MemberInfo, PartyRole: p.PartyRole})
+		}
+	}
+	if len(targets) > 0 {
+		return targets
+	}
+
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
+	for _, a := range attends {
+		targets = append(targets, payoutTarget{MemberInfo: a.MemberInfo})
+	}
+	return targets
+}
+
+func describeDistributionRule(rule model.DistributionRule) string {
+	desc := fmt.Sprintf("%s (%s", rule.Name, rule.DistributionType)
+	switch rule.DistributionType {
+	case distributionTypeRoleRatio:
+		desc += fmt.Sprintf(", 대상 %.2g배", rule.Amount)
+	case distributionTypeFixed:
+		desc += fmt.Sprintf(", 대상 %s메소", formatMeso(fixedDistributionAmount(rule)))
+	}
+	if rule.TargetPartyRole != "" {
+		desc += fmt.Sprintf(", 역할 %s", rule.TargetPartyRole)
+	}
+	if rule.TargetLevel > 0 {
+		desc += fmt.Sprintf(", Lv %d 이상", rule.TargetLevel)
+	}
+	if rule.TargetAttackPower > 0 {
+		desc += fmt.Sprintf(", 공격력 %d 이상 조건은 지원하지 않아 무시됨", rule.TargetAttackPower)
+	}
+	return desc + ")"
+}
+
+func formatMeso(amount int64) string {
+	if amount < 0 {
+		return "-" + formatMeso(-amount)
+	}
+	str := strconv.FormatInt(amount, 10)
+	for idx := len(str) - 3; idx > 0; idx -= 3 {
+		str = str[:idx] + "," + str[idx:]
+	}
+	return str
+}
+
+func renderRaidPayouts(info model.RaidInfo, payouts []model.RaidPayout, mention bool) string {
+	var sb strings.Builder
+	var sum int64
+	for _, p := range payouts {
+		name := p.Nickname
+		if mention {
+			name = p.Mention
+		}
+		sb.WriteString(fmt.Sprintf("* %s / %s: %s메소\n", p.SubRoleName, name, formatMeso(p.Amount)))
+		sum += p.Amount
+	}
+	if info.LootAmount > sum {
+		sb.WriteString(fmt.Sprintf("* 잔여: %s메소\n", formatMeso(info.LootAmount-sum)))
+	}
+	return sb.String()
+}
+
+func raidDistributionHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string, notice string) {
+	// get info
+	var info model.RaidInfo
+	err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Preload("DistributionRule").Preload("Payouts").First(&info, infoID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	msg := ""
+	if notice != "" {
+		msg += notice + "\n\n"
+	}
+	msg += fmt.Sprintf("**[%s] %s (%d트라이) 분배 관리**\n\n",
+		info.RaidSchedule.Raid.RaidName, info.RaidSchedule.StartTime.In(loc).Format("2006-01-02 15:04"), info.RaidSchedule.TryCount)
+	if info.DistributionRuleID == 0 {
+		msg += "* 분배 규칙: 선택 안됨\n"
+	} else {
+		msg += fmt.Sprintf("* 분배 규칙: %s\n", describeDistributionRule(info.DistributionRule))
+	}
+	msg += fmt.Sprintf("* 판매 금액: %s메소\n", formatMeso(info.LootAmount))
+
+	if len(info.Payouts) > 0 {
+		msg += "\n**분배 내역**\n"
+		msg += renderRaidPayouts(info, info.Payouts, false)
+	}
+
+	// list rules; latest 25
+	var rules []model.DistributionRule
+	rdb.Order("id desc").Limit(25).Find(&rules)
+
+	var components []discordgo.MessageComponent
+	if len(rules) > 0 {
+		var selectOptions []discordgo.SelectMenuOption
+		for _, r := range rules {
+			selectOptions = append(selectOptions, discordgo.SelectMenuOption{
+				Label:   describeDistributionRule(r),
+				Value:   fmt.Sprintf("%d", r.ID),
+				Default: r.ID == info.DistributionRuleID,
+			})
+		}
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    fmt.Sprintf("admin-info-distribution-select-rule_%d", info.ID),
+					Placeholder: "분배 규칙 선택",
+					Options:     selectOptions,
+				},
+			},
+		})
+	}
+	components = append(components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "분배 계산",
+				Style:    discordgo.PrimaryButton,
+				CustomID: fmt.Sprintf("admin-info-distribution-calc_%d", info.ID),
+			},
+			discordgo.Button{
+				Label:    "판매 금액 입력",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("admin-info-loot_%d", info.ID),
+			},
+			discordgo.Button{
+				Label:    "규칙 추가",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("admin-distribution-add-rule_%d", info.ID),
+			},
+			discordgo.Button{
+				Label:    "레이드 기록으로 돌아가기",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("admin-info-view_%d", info.ID),
+			},
+		},
+	})
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: components,
+		},
+	})
+}
+
+func raidDistributionSelectRuleHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string) {
+	selectMenuValues := i.MessageComponentData().Values
+	if len(selectMenuValues) != 1 {
+		return
+	}
+
+	// get rule
+	var rule model.DistributionRule
+	err := rdb.First(&rule, selectMenuValues[0]).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	// update info
+	rdb.Model(&model.RaidInfo{}).Where("id = ?", infoID).Update("distribution_rule_id", rule.ID)
+
+	raidDistributionHandler(s, i, infoID, fmt.Sprintf("분배 규칙 '%s'가 적용되었습니다.", rule.Name))
+}
+
+func raidDistributionCalcHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string) {
+	// get info
+	var info model.RaidInfo
+	err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Preload("DistributionRule").First(&info, infoID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	if info.DistributionRuleID == 0 {
+		raidDistributionHandler(s, i, infoID, "분배 규칙을 먼저 선택하세요.")
+		return
+	}
+	if info.LootAmount <= 0 {
+		raidDistributionHandler(s, i, infoID, "판매 금액을 먼저 입력하세요.")
+		return
+	}
+
+	payouts := calculateRaidPayouts(info.DistributionRule, info.LootAmount, listPayoutTargets(info))
+	if len(payouts) == 0 {
+		raidDistributionHandler(s, i, infoID, "분배 대상 참가자가 없습니다.")
+		return
+	}
+
+	// replace payouts
+	rdb.Where("raid_info_id = ?", info.ID).Delete(&model.RaidPayout{})
+	for idx := range payouts {
+		payouts[idx].RaidInfoID = info.ID
+	}
+	rdb.Create(&payouts)
+
+	notice := "분배 내역이 계산되어 레이드 정보 채널에 게시되었습니다."
+	if err := updateRaidInfoMessage(s, info); err != nil {
+		fmt.Println("failed to publish payouts:", err)
+		notice = "분배 내역이 계산되었지만 게시에 실패했습니다."
+	}
+
+	raidDistributionHandler(s, i, infoID, notice)
+}
+
+func raidDistributionRuleModalHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string) {
+	modalData := i.ModalSubmitData().Components
+
+	var name, distributionType, amount, targetPartyRole, targetLevel string
+	for _, comp := range modalData {
+		if ar, ok := comp.(*discordgo.ActionsRow); ok {
+			if ti, ok := ar.Components[0].(*discordgo.TextInput); ok {
+				switch ti.CustomID {
+				case "rule-name":
+					name = strings.TrimSpace(ti.Value)
+				case "distribution-type":
+					distributionType = strings.TrimSpace(ti.Value)
+				case "amount":
+					amount = strings.TrimSpace(ti.Value)
+				case "target-party-role":
+					targetPartyRole = strings.TrimSpace(ti.Value)
+				case "target-level":
+					targetLevel = strings.TrimSpace(ti.Value)
+				}
+			}
+		}
+	}
+
+	if name == "" {
+		raidDistributionHandler(s, i, infoID, "분배 규칙 이름을 입력해주세요.")
+		return
+	}
+	if !slices.Contains(distributionTypeList, distributionType) {
+		raidDistributionHandler(s, i, infoID, fmt.Sprintf("분배 방식 '%s'를 찾을 수 없습니다. (%s)", distributionType, strings.Join(distributionTypeList, "/")))
+		return
+	}
+
+	// fixed amounts are mesos and kept exact, ratios are weights
+	amount = strings.ReplaceAll(amount, ",", "")
+	var ratio float64
+	var fixed int64
+	var err error
+	if distributionType == distributionTypeFixed {
+		fixed, err = strconv.ParseInt(amount, 10, 64)
+		if err != nil || fixed < 0 {
+			raidDistributionHandler(s, i, infoID, "고정 금액이 올바르지 않습니다.")
+			return
+		}
+	} else if amount != "" {
+		ratio, err = strconv.ParseFloat(amount, 32)
+		if err != nil || ratio < 0 {
+			raidDistributionHandler(s, i, infoID, "비율이 올바르지 않습니다.")
+			return
+		}
+	}
+	if targetPartyRole != "" && !slices.Contains(partyRoleList, targetPartyRole) {
+		raidDistributionHandler(s, i, infoID, fmt.Sprintf("파티 역할 '%s'를 찾을 수 없습니다. (%s)", targetPartyRole, strings.Join(partyRoleList, "/")))
+		return
+	}
+	level := 0
+	if targetLevel != "" {
+		level, err = strconv.Atoi(targetLevel)
+		if err != nil {
+			raidDistributionHandler(s, i, infoID, "대상 최소 레벨이 올바르지 않습니다.")
+			return
+		}
+	}
+
+	// create rule
+	rule := model.DistributionRule{
+		Name:             name,
+		TargetPartyRole:  targetPartyRole,
+		TargetLevel:      level,
+		DistributionType: distributionType,
+		Amount:           float32(ratio),
+		FixedAmount:      fixed,
+	}
+	rdb.Create(&rule)
+
+	raidDistributionHandler(s, i, infoID, fmt.Sprintf("분배 규칙 '%s'가 추가되었습니다.", name))
+}
+
+func raidLootModalHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string) {
+	modalData := i.ModalSubmitData().Components
+
+	var lootAmount string
+	for _, comp := range modalData {
+		if ar, ok := comp.(*discordgo.ActionsRow); ok {
+			if ti, ok := ar.Components[0].(*discordgo.TextInput); ok {
+				if ti.CustomID == "loot-amount" {
+					lootAmount = ti.Value
+				}
+			}
+		}
+	}
+
+	amount, err := strconv.ParseInt(strings.ReplaceAll(strings.TrimSpace(lootAmount), ",", ""), 10, 64)
+	if err != nil || amount < 0 {
+		raidDistributionHandler(s, i, infoID, "판매 금액이 올바르지 않습니다.")
+		return
+	}
+
+	// update info
+	rdb.Model(&model.RaidInfo{}).Where("id = ?", infoID).Update("loot_amount", amount)
+
+	raidDistributionHandler(s, i, infoID, fmt.Sprintf("판매 금액이 %s메소로 저장되었습니다.", formatMeso(amount)))
+}
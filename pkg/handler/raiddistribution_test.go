@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sokdak/eternity-bot/pkg/model"
+)
+
+func TestCalculateRaidPayouts(t *testing.T) {
+	front := payoutTarget{MemberInfo: model.MemberInfo{Nickname: "front", Level: 160}, PartyRole: "전방"}
+	dealer := payoutTarget{MemberInfo: model.MemberInfo{Nickname: "dealer", Level: 120}, PartyRole: "메인딜"}
+	sub := payoutTarget{MemberInfo: model.MemberInfo{Nickname: "sub", Level: 100}, PartyRole: "서브딜"}
+
+	tests := []struct {
+		name    string
+		rule    model.DistributionRule
+		total   int64
+		targets []payoutTarget
+		want    []int64
+	}{
+		{
+			name:  "no targets",
+			rule:  model.DistributionRule{DistributionType: distributionTypeEqual},
+			total: 100,
+		},
+		{
+			name:    "no loot",
+			rule:    model.DistributionRule{DistributionType: distributionTypeEqual},
+			targets: []payoutTarget{front},
+		},
+		{
+			name:    "equal",
+			rule:    model.DistributionRule{DistributionType: distributionTypeEqual},
+			total:   100,
+			targets: []payoutTarget{front, dealer, sub},
+			want:    []int64{33, 33, 33},
+		},
+		{
+			name:    "role ratio by party role",
+			rule:    model.DistributionRule{DistributionType: distributionTypeRoleRatio, TargetPartyRole: "전방", Amount: 2},
+			total:   300,
+			targets: []payoutTarget{front, dealer},
+			want:    []int64{200, 100},
+		},
+		{
+			name:    "role ratio by level",
+			rule:    model.DistributionRule{DistributionType: distributionTypeRoleRatio, TargetLevel: 120, Amount: 3},
+			total:   700,
+			targets: []payoutTarget{front, dealer, sub},
+			want:    []int64{300, 300, 100},
+		},
+		{
+			name:    "fixed with the rest split",
+			rule:    model.DistributionRule{DistributionType: distributionTypeFixed, TargetLevel: 150, FixedAmount: 50},
+			total:   200,
+			targets: []payoutTarget{front, dealer, sub},
+			want:    []int64{50, 75, 75},
+		},
+		{
+			name:    "fixed capped by the total",
+			rule:    model.DistributionRule{DistributionType: distributionTypeFixed, FixedAmount: 500},
+			total:   600,
+			targets: []payoutTarget{front, dealer},
+			want:    []int64{500, 100},
+		},
+		{
+			name:    "fixed above float32 precision",
+			rule:    model.DistributionRule{DistributionType: distributionTypeFixed, TargetPartyRole: "전방", FixedAmount: 16_777_217},
+			total:   50_000_001,
+			targets: []payoutTarget{front, dealer},
+			want:    []int64{16_777_217, 33_222_784},
+		},
+		{
+			name:    "fixed saved before FixedAmount",
+			rule:    model.DistributionRule{DistributionType: distributionTypeFixed, TargetPartyRole: "전방", Amount: 100},
+			total:   300,
+			targets: []payoutTarget{front, dealer},
+			want:    []int64{100, 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			for idx, p := range calculateRaidPayouts(tt.rule, tt.total, tt.targets) {
+				if p.Nickname != tt.targets[idx].Nickname || p.PartyRole != tt.targets[idx].PartyRole {
+					t.Errorf("payout %d: got %s/%s, want %s/%s", idx, p.Nickname, p.PartyRole, tt.targets[idx].Nickname, tt.targets[idx].PartyRole)
+				}
+				got = append(got, p.Amount)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// raidLedgerPeriods are the periods (in days) selectable in the admin statistics view, 0 means all time.
+var raidLedgerPeriods = []int{30, 90, 0}
+
+type raidLedgerStats struct {
+	Nickname      string
+	SignedUp      int
+	Attended      int
+	WalkIn        int
+	NoShow        int
+	CanceledEarly int
+	CanceledLate  int
+	NotFormed     int
+	// Pending sign-ups are still waitlisted or not checked at the entrance yet.
+	Pending int
+}
+
+// expected is the number of sign-ups the member was expected to show up for.
+func (st raidLedgerStats) expected() int {
+	return st.SignedUp - st.CanceledEarly - st.NotFormed - st.Pending
+}
+
+func (st raidLedgerStats) attendanceRate() float64 {
+	if st.expected() <= 0 {
+		return 0
+	}
+	return float64(st.Attended-st.WalkIn) / float64(st.expected()) * 100
+}
+
+func (st raidLedgerStats) noShowRate() float64 {
+	if st.expected() <= 0 {
+		return 0
+	}
+	return float64(st.NoShow+st.CanceledLate) / float64(st.expected()) * 100
+}
+
+func (st raidLedgerStats) describe() string {
+	return fmt.Sprintf("신청 %d / 참석 %d (미신청 %d) / 불참 %d / 조기취소 %d / 지각취소 %d / 미편성 %d / 미확정 %d - 출석률 %.0f%%, 노쇼율 %.0f%%",
+		st.SignedUp, st.Attended, st.WalkIn, st.NoShow, st.CanceledEarly, st.CanceledLate, st.NotFormed, st.Pending,
+		st.attendanceRate(), st.noShowRate())
+}
+
+// recordRaidLedger sets the outcome of the member for the schedule, creating the ledger entry if needed.
+func recordRaidLedger(schedule model.RaidSchedule, mi model.MemberInfo, outcome string, signedUp bool) {
+	var entry model.RaidAttendLedger
+	err := rdb.Where("raid_schedule_id = ? AND mention = ?", schedule.ID, mi.Mention).First(&entry).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Println("failed to get raid ledger:", err)
+		return
+	}
+
+	entry.RaidScheduleID = schedule.ID
+	entry.StartTime = schedule.StartTime
+	entry.MemberInfo = mi
+	entry.SignedUp = entry.SignedUp || signedUp
+	entry.Outcome = outcome
+	rdb.Save(&entry)
+}
+
+func deleteRaidLedger(scheduleID uint, mention string) {
+	rdb.Where("raid_schedule_id = ? AND mention = ?", scheduleID, mention).Delete(&model.RaidAttendLedger{})
+}
+
+// raidCancelOutcome tells whether a cancel now is early (before subscription close) or late.
+func raidCancelOutcome(schedule model.RaidSchedule) string {
+	if time.Now().Before(schedule.SubscriptionEndTime) {
+		return model.RaidLedgerCanceledEarly
+	}
+	return model.RaidLedgerCanceledLate
+}
+
+// recordRaidLedgerAttendance records the outcome of every member in the attendance snapshot.
+func recordRaidLedgerAttendance(schedule model.RaidSchedule, attendances []model.RaidAttendance) {
+	// walk-ins are re-created from the latest snapshot
+	rdb.Where("raid_schedule_id = ? AND signed_up = ?", schedule.ID, false).Delete(&model.RaidAttendLedger{})
+
+	for _, a := range attendances {
+		switch a.Status {
+		case model.RaidAttendancePresent:
+			recordRaidLedger(schedule, a.MemberInfo, model.RaidLedgerAttended, true)
+		case model.RaidAttendanceAbsent:
+			recordRaidLedger(schedule, a.MemberInfo, model.RaidLedgerNoShow, true)
+		case model.RaidAttendanceWalkIn:
+			recordRaidLedger(schedule, a.MemberInfo, model.RaidLedgerAttended, false)
+		}
+	}
+}
+
+func summarizeRaidLedger(entries []model.RaidAttendLedger) map[string]*raidLedgerStats {
+	stats := make(map[string]*raidLedgerStats)
+	for _, e := range entries {
+		st, ok := stats[e.Mention]
+		if !ok {
+			st = &raidLedgerStats{}
+			stats[e.Mention] = st
+		}
+		st.Nickname = e.Nickname
+
+		if e.SignedUp {
+			st.SignedUp++
+		}
+		switch e.Outcome {
+		case model.RaidLedgerAttended:
+			st.Attended++
+			if !e.SignedUp {
+				st.WalkIn++
+			}
+		case model.RaidLedgerNoShow:
+			st.NoShow++
+		case model.RaidLedgerCanceledEarly:
+			st.CanceledEarly++
+		case model.RaidLedgerCanceledLate:
+			st.CanceledLate++
+		case model.RaidLedgerNotFormed:
+			st.NotFormed++
+		case model.RaidLedgerSignedUp:
+			st.Pending++
+		}
+	}
+	return stats
+}
+
+func raidMyHistoryHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	memberInfo := cache.GetGuildMember(i.User.ID)
+	if memberInfo == nil {
+		return
+	}
+	m, err := GetMemberInfoFromMember(memberInfo)
+	if err != nil {
+		return
+	}
+
+	// get ledger
+	var entries []model.RaidAttendLedger
+	rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").Where("mention = ?", m.Mention).Order("start_time desc").Find(&entries)
+
+	msg := fmt.Sprintf("**[%s 님의 레이드 기록]**\n", m.Nickname)
+	if len(entries) == 0 {
+		msg += "레이드 기록이 없습니다.\n"
+	} else {
+		st := summarizeRaidLedger(entries)[m.Mention]
+		msg += st.describe() + "\n\n"
+
+		msg += "**[최근 기록]**\n"
+		for idx, e := range entries {
+			if idx >= 10 {
+				break
+			}
+			msg += fmt.Sprintf("* [%s] %s (%d트라이) - %s\n",
+				e.RaidSchedule.Raid.RaidName, e.StartTime.In(loc).Format("2006-01-02 15:04"), e.RaidSchedule.TryCount, e.Outcome)
+		}
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "처음으로 돌아가기",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "user-landing-page",
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func raidLedgerStatsHandler(s *discordgo.Session, i *discordgo.InteractionCreate, days string) {
+	d, err := strconv.Atoi(days)
+	if err != nil {
+		return
+	}
+
+	// get ledger
+	query := rdb.Model(&model.RaidAttendLedger{})
+	if d > 0 {
+		query = query.Where("start_time >= ?", time.Now().AddDate(0, 0, -d).UTC())
+	}
+	var entries []model.RaidAttendLedger
+	query.Where("start_time < ?", time.Now().UTC()).Find(&entries)
+
+	stats := summarizeRaidLedger(entries)
+	var list []*raidLedgerStats
+	for _, st := range stats {
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].SignedUp != list[j].SignedUp {
+			return list[i].SignedUp > list[j].SignedUp
+		}
+		return list[i].Nickname < list[j].Nickname
+	})
+
+	period := "전체 기간"
+	if d > 0 {
+		period = fmt.Sprintf("최근 %d일", d)
+	}
+	msg := fmt.Sprintf("**[레이드 참석 통계 - %s]**\n", period)
+	if len(list) == 0 {
+		msg += "기록이 없습니다.\n"
+	}
+	for idx, st := range list {
+		line := fmt.Sprintf("* %s: %s\n", st.Nickname, st.describe())
+		if len(msg)+len(line) > 1900 {
+			msg += fmt.Sprintf("외 %d명\n", len(list)-idx)
+			break
+		}
+		msg += line
+	}
+
+	var buttons []discordgo.MessageComponent
+	for _, p := range raidLedgerPeriods {
+		label := "전체 기간"
+		if p > 0 {
+			label = fmt.Sprintf("최근 %d일", p)
+		}
+		style := discordgo.SecondaryButton
+		if p == d {
+			style = discordgo.PrimaryButton
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    label,
+			Style:    style,
+			CustomID: fmt.Sprintf("admin-stats_%d", p),
+		})
+	}
+	buttons = append(buttons, discordgo.Button{
+		Label:    "처음으로 돌아가기",
+		Style:    discordgo.SecondaryButton,
+		CustomID: "admin-landing-page",
+	})
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: buttons,
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+)
+
+func describeRaidSchedule(sc model.RaidSchedule) string {
+	return fmt.Sprintf("[%s] %s (%d트라이)", sc.Raid.RaidName, sc.StartTime.In(loc).Format("2006-01-02 15:04"), sc.TryCount)
+}
+
+// diffRaidSchedule returns the before/after lines of the fields members care about.
+func diffRaidSchedule(before, after model.RaidSchedule) []string {
+	var diff []string
+	if !before.StartTime.Equal(after.StartTime) {
+		diff = append(diff, fmt.Sprintf("* 시작 시간: %s → %s",
+			before.StartTime.In(loc).Format("2006-01-02 15:04"), after.StartTime.In(loc).Format("2006-01-02 15:04")))
+	}
+	if before.TryCount != after.TryCount {
+		diff = append(diff, fmt.Sprintf("* 트라이: %d트라이 → %d트라이", before.TryCount, after.TryCount))
+	}
+	return diff
+}
+
+func listScheduleAttendMentions(scheduleID uint) []string {
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ?", scheduleID, false).Order("id asc").Find(&attends)
+
+	var mentions []string
+	for _, a := range attends {
+		mentions = append(mentions, a.Mention)
+	}
+	return mentions
+}
+
+// notifyRaidScheduleChange DMs the attendees and replies under the subscription message
+// when the start time or try count of the schedule has changed.
+func notifyRaidScheduleChange(s *discordgo.Session, before, after model.RaidSchedule) {
+	diff := diffRaidSchedule(before, after)
+	if len(diff) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("%s 레이드 일정이 변경되었습니다.\n", describeRaidSchedule(before))
+	for _, d := range diff {
+		msg += d + "\n"
+	}
+	notifyRaidScheduleAttendees(s, after, msg)
+}
+
+// notifyRaidScheduleRemoval DMs the attendees that the schedule is removed,
+// and turns the subscription message into the cancellation notice without the sign-up buttons.
+func notifyRaidScheduleRemoval(s *discordgo.Session, schedule model.RaidSchedule) {
+	msg := fmt.Sprintf("%s 레이드 일정이 취소되었습니다.", describeRaidSchedule(schedule))
+	dmRaidScheduleAttendees(s, schedule, msg)
+
+	if schedule.MessageID == "" {
+		return
+	}
+	components := []discordgo.MessageComponent{}
+	_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    environment.DiscordGuildRaidSubscriptionChannelID,
+		ID:         schedule.MessageID,
+		Content:    &msg,
+		Components: &components,
+	})
+	if err != nil {
+		fmt.Println("failed to edit subscription message:", err)
+	}
+}
+
+func notifyRaidScheduleAttendees(s *discordgo.Session, schedule model.RaidSchedule, msg string) {
+	dmRaidScheduleAttendees(s, schedule, msg)
+	sendRaidScheduleReply(s, schedule, msg)
+}
+
+func dmRaidScheduleAttendees(s *discordgo.Session, schedule model.RaidSchedule, msg string) {
+	for _, mention := range listScheduleAttendMentions(schedule.ID) {
+		sendMessage(s, userIDFromMention(mention), msg)
+	}
+}
@@ -0,0 +1,981 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const maxPartyMemberCount = 6
+
+// frontPartyWarriorCount is the number of 전사 the auto formation tries to put in the front party.
+const frontPartyWarriorCount = 3
+
+var partyRoleList = []string{"전방", "메인딜", "서브딜", "예비"}
+
+func listRaidParties(infoID uint) []model.RaidPartyInfo {
+	var parties []model.RaidPartyInfo
+	rdb.Preload("Members").Where("raid_info_id = ?", infoID).Find(&parties)
+
+	// sort by party order, then by member id
+	sort.Slice(parties, func(i, j int) bool {
+		return parties[i].Order < parties[j].Order
+	})
+	for _, p := range parties {
+		sort.Slice(p.Members, func(i, j int) bool {
+			return p.Members[i].ID < p.Members[j].ID
+		})
+	}
+	return parties
+}
+
+func listUnassignedAttends(attends []model.RaidAttend, parties []model.RaidPartyInfo) []model.RaidAttend {
+	var assigned []string
+	for _, p := range parties {
+		for _, m := range p.Members {
+			assigned = append(assigned, m.Nickname)
+		}
+	}
+
+	var unassigned []model.RaidAttend
+	for _, a := range attends {
+		if !slices.Contains(assigned, a.Nickname) {
+			unassigned = append(unassigned, a)
+		}
+	}
+	return unassigned
+}
+
+func renderRaidParties(parties []model.RaidPartyInfo, mention bool) string {
+	var sb strings.Builder
+	for _, p := range parties {
+		sb.WriteString(fmt.Sprintf("**%d파티 - %s** (%d/%d)\n", p.Order, p.PartyRole, len(p.Members), maxPartyMemberCount))
+		for _, m := range p.Members {
+			name := m.Nickname
+			if mention {
+				name = m.Mention
+			}
+			sb.WriteString(fmt.Sprintf("* %s / %d / %s", m.SubRoleName, m.Level, name))
+			if m.Role != "" {
+				sb.WriteString(fmt.Sprintf(" (%s)", m.Role))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// updateRaidInfoMessage re-renders the raid info channel message of the given raid info.
+func updateRaidInfoMessage(s *discordgo.Session, info model.RaidInfo) error {
+	msg := fmt.Sprintf("**[%s] %s - %d트라이**\n\n",
+		info.RaidSchedule.Raid.RaidName, info.RaidSchedule.StartTime.In(loc).Format("2006-01-02"), info.RaidSchedule.TryCount)
+
+	parties := listRaidParties(info.ID)
+	if len(parties) > 0 {
+		msg += "**[파티 구성]**\n"
+		msg += renderRaidParties(parties, true)
+	}
+
+	var attendances []model.RaidAttendance
+	rdb.Where("raid_info_id = ?", info.ID).Find(&attendances)
+	if len(attendances) > 0 {
+		msg += "**[출석 체크]**\n"
+		msg += renderRaidAttendances(attendances, false)
+		msg += "\n"
+	}
+
+	var payouts []model.RaidPayout
+	rdb.Where("raid_info_id = ?", info.ID).Find(&payouts)
+	if len(payouts) > 0 {
+		msg += fmt.Sprintf("**[분배 내역]** 총 %s메소\n", formatMeso(info.LootAmount))
+		msg += renderRaidPayouts(info, payouts, true)
+	}
+
+	if _, err := s.ChannelMessageEdit(environment.DiscordGuildRaidInfoChannelID, info.MessageID, msg); err != nil {
+		return fmt.Errorf("failed to edit raid info message: %w", err)
+	}
+	return nil
+}
+
+func raidPartyFormationHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string, notice string) {
+	// get info
+	var info model.RaidInfo
+	err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&info, infoID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	// get attend
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
+
+	parties := listRaidParties(info.ID)
+	unassigned := listUnassignedAttends(attends, parties)
+
+	msg := ""
+	if notice != "" {
+		msg += notice + "\n\n"
+	}
+	msg += fmt.Sprintf("**[%s] %s (%d트라이) 파티 구성**\n참가자 %d명 / 미편성 %d명\n\n",
+		info.RaidSchedule.Raid.RaidName, info.RaidSchedule.StartTime.In(loc).Format("2006-01-02 15:04"), info.RaidSchedule.TryCount,
+		len(attends), len(unassigned))
+	msg += renderRaidParties(parties, false)
+
+	if len(unassigned) > 0 {
+		msg += "**미편성 참가자**\n"
+		for _, a := range unassigned {
+			msg += fmt.Sprintf("* %s / %d / %s%s\n", a.SubRoleName, a.Level, a.Nickname, describeAttendPreference(a))
+		}
+	}
+
+	// create selections
+	var selectOptions []discordgo.SelectMenuOption
+	for _, p := range parties {
+		selectOptions = append(selectOptions, discordgo.SelectMenuOption{
+			Label: fmt.Sprintf("%d파티 (%s, %d명)", p.Order, p.PartyRole, len(p.Members)),
+			Value: fmt.Sprintf("%d", p.ID),
+		})
+	}
+	selectOptions = append(selectOptions, discordgo.SelectMenuOption{
+		Label: "새 파티 추가",
+		Value: "new",
+	})
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.SelectMenu{
+							CustomID:    fmt.Sprintf("admin-party-select-party_%d", info.ID),
+							Placeholder: "편집할 파티 선택",
+							Options:     selectOptions,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "파티 구성 게시",
+							Style:    discordgo.PrimaryButton,
+							CustomID: fmt.Sprintf("admin-party-publish_%d", info.ID),
+						},
+						discordgo.Button{
+							Label:    "자동 편성",
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("admin-party-auto_%d", info.ID),
+						},
+						discordgo.Button{
+							Label:    "레이드 기록으로 돌아가기",
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("admin-info-view_%d", info.ID),
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func raidPartySelectHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string) {
+	selectMenuValues := i.MessageComponentData().Values
+	if len(selectMenuValues) != 1 {
+		return
+	}
+
+	if selectMenuValues[0] != "new" {
+		raidPartyEditHandler(s, i, selectMenuValues[0], 0)
+		return
+	}
+
+	// get info
+	var info model.RaidInfo
+	err := rdb.First(&info, infoID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	// create new party at the end
+	parties := listRaidParties(info.ID)
+	role := partyRoleList[1]
+	if len(parties) == 0 {
+		role = partyRoleList[0]
+	}
+	party := model.RaidPartyInfo{
+		RaidInfoID: info.ID,
+		Order:      len(parties) + 1,
+		PartyRole:  role,
+	}
+	rdb.Create(&party)
+
+	raidPartyEditHandler(s, i, fmt.Sprintf("%d", party.ID), 0)
+}
+
+// raidPartyEditHandler shows the party editor, the unassigned attendees that do not fit in the select menu are paged.
+func raidPartyEditHandler(s *discordgo.Session, i *discordgo.InteractionCreate, partyID string, page int) {
+	// get party
+	var party model.RaidPartyInfo
+	err := rdb.Preload("Members").Preload("RaidInfo").Preload("RaidInfo.RaidSchedule").Preload("RaidInfo.RaidSchedule.Raid").First(&party, partyID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	sort.Slice(party.Members, func(i, j int) bool {
+		return party.Members[i].ID < party.Members[j].ID
+	})
+
+	// get attend
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", party.RaidInfo.RaidScheduleID, false, false).Find(&attends)
+	unassigned := listUnassignedAttends(attends, listRaidParties(party.RaidInfoID))
+
+	msg := fmt.Sprintf("**[%s] %s (%d트라이) %d파티 편집**\n\n",
+		party.RaidInfo.RaidSchedule.Raid.RaidName, party.RaidInfo.RaidSchedule.StartTime.In(loc).Format("2006-01-02 15:04"),
+		party.RaidInfo.RaidSchedule.TryCount, party.Order)
+	msg += renderRaidParties([]model.RaidPartyInfo{party}, false)
+	msg += "파티원은 최대 6명까지 선택할 수 있으며, 선택 해제된 파티원은 미편성으로 돌아갑니다."
+
+	// create member selections; current members first, then unassigned attendees
+	var memberOptions []discordgo.SelectMenuOption
+	for _, m := range party.Members {
+		memberOptions = append(memberOptions, discordgo.SelectMenuOption{
+			Label:   fmt.Sprintf("%s / %d / %s", m.SubRoleName, m.Level, m.Nickname),
+			Value:   m.Nickname,
+			Default: true,
+		})
+	}
+	// the select menu takes up to 25 options, the current members are always shown
+	pageSize := 25 - len(party.Members)
+	pageCount := max(1, (len(unassigned)+pageSize-1)/pageSize)
+	page = min(max(page, 0), pageCount-1)
+	shown := unassigned[page*pageSize : min((page+1)*pageSize, len(unassigned))]
+	if pageCount > 1 {
+		msg += fmt.Sprintf("\n미편성 %d명 중 %d~%d번째를 표시합니다. (외 %d명은 이전/다음 버튼으로 선택)",
+			len(unassigned), page*pageSize+1, page*pageSize+len(shown), len(unassigned)-len(shown))
+	}
+	for _, a := range shown {
+		option := discordgo.SelectMenuOption{
+			Label: fmt.Sprintf("%s / %d / %s", a.SubRoleName, a.Level, a.Nickname),
+			Value: a.Nickname,
+		}
+		if desc := describeAttendPreference(a); desc != "" {
+			option.Description = strings.TrimSpace(desc)
+		}
+		memberOptions = append(memberOptions, option)
+	}
+
+	// create role selections
+	var roleOptions []discordgo.SelectMenuOption
+	for _, r := range partyRoleList {
+		roleOptions = append(roleOptions, discordgo.SelectMenuOption{
+			Label:   r,
+			Value:   r,
+			Default: r == party.PartyRole,
+		})
+	}
+
+	var components []discordgo.MessageComponent
+	if len(memberOptions) > 0 {
+		minValues := 0
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    fmt.Sprintf("admin-party-set-members_%d", party.ID),
+					Placeholder: "파티원 선택",
+					MinValues:   &minValues,
+					MaxValues:   min(maxPartyMemberCount, len(memberOptions)),
+					Options:     memberOptions,
+				},
+			},
+		})
+	}
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "파티 삭제",
+			Style:    discordgo.DangerButton,
+			CustomID: fmt.Sprintf("admin-party-remove_%d", party.ID),
+		},
+		discordgo.Button{
+			Label:    "파티 목록으로 돌아가기",
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("admin-info-party-formation_%d", party.RaidInfoID),
+		},
+	}
+	if pageCount > 1 {
+		buttons = append(buttons,
+			discordgo.Button{
+				Label:    "이전 미편성",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("admin-party-edit-page_%d_%d", party.ID, page-1),
+				Disabled: page == 0,
+			},
+			discordgo.Button{
+				Label:    "다음 미편성",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("admin-party-edit-page_%d_%d", party.ID, page+1),
+				Disabled: page == pageCount-1,
+			},
+		)
+	}
+	components = append(components,
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    fmt.Sprintf("admin-party-set-role_%d", party.ID),
+					Placeholder: "파티 역할 선택",
+					Options:     roleOptions,
+				},
+			},
+		},
+		discordgo.ActionsRow{
+			Components: buttons,
+		},
+	)
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: components,
+		},
+	})
+}
+
+func raidPartySetMembersHandler(s *discordgo.Session, i *discordgo.InteractionCreate, partyID string) {
+	selected := i.MessageComponentData().Values
+	if len(selected) > maxPartyMemberCount {
+		return
+	}
+
+	// get party
+	var party model.RaidPartyInfo
+	err := rdb.Preload("Members").Preload("RaidInfo").First(&party, partyID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	sort.Slice(party.Members, func(i, j int) bool {
+		return party.Members[i].ID < party.Members[j].ID
+	})
+
+	// get attend
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", party.RaidInfo.RaidScheduleID, false, false).Find(&attends)
+
+	// keep the current order of existing members, then append newly selected attendees
+	var candidates []model.MemberInfo
+	for _, m := range party.Members {
+		candidates = append(candidates, m.MemberInfo)
+	}
+	for _, a := range listUnassignedAttends(attends, listRaidParties(party.RaidInfoID)) {
+		candidates = append(candidates, a.MemberInfo)
+	}
+
+	var members []model.RaidPartyMemberInfo
+	for _, c := range candidates {
+		if !slices.Contains(selected, c.Nickname) {
+			continue
+		}
+		role := "파티원"
+		if len(members) == 0 {
+			role = "파티장"
+		}
+		members = append(members, model.RaidPartyMemberInfo{
+			RaidPartyInfoID: party.ID,
+			MemberInfo:      c,
+			Role:            role,
+		})
+	}
+
+	// replace members
+	rdb.Where("raid_party_info_id = ?", party.ID).Delete(&model.RaidPartyMemberInfo{})
+	if len(members) > 0 {
+		rdb.Create(&members)
+	}
+
+	raidPartyEditHandler(s, i, partyID, 0)
+}
+
+func raidPartySetRoleHandler(s *discordgo.Session, i *discordgo.InteractionCreate, partyID string) {
+	selectMenuValues := i.MessageComponentData().Values
+	if len(selectMenuValues) != 1 || !slices.Contains(partyRoleList, selectMenuValues[0]) {
+		return
+	}
+
+	// get party
+	var party model.RaidPartyInfo
+	err := rdb.First(&party, partyID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	// update party
+	party.PartyRole = selectMenuValues[0]
+	rdb.Save(&party)
+
+	raidPartyEditHandler(s, i, partyID, 0)
+}
+
+func raidPartyRemoveHandler(s *discordgo.Session, i *discordgo.InteractionCreate, partyID string) {
+	// get party
+	var party model.RaidPartyInfo
+	err := rdb.First(&party, partyID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	// delete party and members
+	rdb.Where("raid_party_info_id = ?", party.ID).Delete(&model.RaidPartyMemberInfo{})
+	rdb.Delete(&party)
+
+	// renumber remaining parties
+	for idx, p := range listRaidParties(party.RaidInfoID) {
+		if p.Order != idx+1 {
+			p.Order = idx + 1
+			rdb.Model(&p).Update("order", p.Order)
+		}
+	}
+
+	raidPartyFormationHandler(s, i, strconv.Itoa(int(party.RaidInfoID)), fmt.Sprintf("%d파티가 삭제되었습니다.", party.Order))
+}
+
+func raidPartyPublishHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string) {
+	// get info
+	var info model.RaidInfo
+	err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&info, infoID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	notice := "레이드 정보 채널에 파티 구성이 게시되었습니다."
+	if err := updateRaidInfoMessage(s, info); err != nil {
+		fmt.Println("failed to publish party formation:", err)
+		notice = "파티 구성 게시에 실패했습니다."
+	}
+
+	raidPartyFormationHandler(s, i, infoID, notice)
+}
+
+// proposeRaidParties splits attendees into balanced parties.
+// 비숍 are spread across parties first, the front party gets up to frontPartyWarriorCount 전사,
+// and the rest are assigned by level to the party with the lowest average level.
+// Flexible members fill the remaining slots last, and backup-only members are put in 예비 parties.
+func proposeRaidParties(attends []model.RaidAttend) []model.RaidPartyInfo {
+	var mains, flexible, backups []model.MemberInfo
+	for _, a := range attends {
+		switch attendPreference(a) {
+		case model.RaidAttendPreferenceFlexible:
+			flexible = append(flexible, a.MemberInfo)
+		case model.RaidAttendPreferenceBackup:
+			backups = append(backups, a.MemberInfo)
+		default:
+			mains = append(mains, a.MemberInfo)
+		}
+	}
+
+	parties := proposeBalancedParties(mains, flexible)
+	for start := 0; start < len(backups); start += maxPartyMemberCount {
+		end := min(start+maxPartyMemberCount, len(backups))
+		party := model.RaidPartyInfo{Order: len(parties) + 1, PartyRole: partyRoleList[3]}
+		for _, m := range backups[start:end] {
+			party.Members = append(party.Members, model.RaidPartyMemberInfo{MemberInfo: m})
+		}
+		parties = append(parties, party)
+	}
+
+	assignPartyLeaders(parties)
+	return parties
+}
+
+func proposeBalancedParties(mains []model.MemberInfo, flexible []model.MemberInfo) []model.RaidPartyInfo {
+	if len(mains)+len(flexible) == 0 {
+		return nil
+	}
+
+	partyCount := (len(mains) + len(flexible) + maxPartyMemberCount - 1) / maxPartyMemberCount
+	parties := make([]model.RaidPartyInfo, partyCount)
+	for idx := range parties {
+		parties[idx].Order = idx + 1
+		parties[idx].PartyRole = partyRoleList[1]
+	}
+	parties[0].PartyRole = partyRoleList[0]
+
+	// sort by level, then by nickname to keep the proposal stable
+	members := append([]model.MemberInfo{}, mains...)
+	sortByLevel := func(ms []model.MemberInfo) {
+		sort.SliceStable(ms, func(i, j int) bool {
+			if ms[i].Level == ms[j].Level {
+				return ms[i].Nickname < ms[j].Nickname
+			}
+			return ms[i].Level > ms[j].Level
+		})
+	}
+	sortByLevel(members)
+
+	var priests, warriors, others []model.MemberInfo
+	for _, m := range members {
+		if m.SubRoleName == "비숍" {
+			priests = append(priests, m)
+		} else if m.MainRoleName == "전사" {
+			warriors = append(warriors, m)
+		} else {
+			others = append(others, m)
+		}
+	}
+
+	levelSum := make([]int, partyCount)
+	assign := func(idx int, m model.MemberInfo) {
+		parties[idx].Members = append(parties[idx].Members, model.RaidPartyMemberInfo{MemberInfo: m})
+		levelSum[idx] += m.Level
+	}
+	lowestAverageParty := func() int {
+		lowest := -1
+		lowestAvg := 0.0
+		for idx, p := range parties {
+			if len(p.Members) >= maxPartyMemberCount {
+				continue
+			}
+			avg := 0.0
+			if len(p.Members) > 0 {
+				avg = float64(levelSum[idx]) / float64(len(p.Members))
+			}
+			if lowest == -1 || avg < lowestAvg || (avg == lowestAvg && len(p.Members) < len(parties[lowest].Members)) {
+				lowest = idx
+				lowestAvg = avg
+			}
+		}
+		return lowest
+	}
+
+	// spread priests across parties
+	for k, m := range priests {
+		idx := k % partyCount
+		if len(parties[idx].Members) >= maxPartyMemberCount {
+			idx = lowestAverageParty()
+		}
+		assign(idx, m)
+	}
+
+	// put warriors in the front party
+	var rest []model.MemberInfo
+	frontWarriors := 0
+	for _, m := range warriors {
+		if frontWarriors < frontPartyWarriorCount && len(parties[0].Members) < maxPartyMemberCount {
+			assign(0, m)
+			frontWarriors++
+			continue
+		}
+		rest = append(rest, m)
+	}
+
+	// even out average level with the rest
+	rest = append(rest, others...)
+	sortByLevel(rest)
+	for _, m := range rest {
+		assign(lowestAverageParty(), m)
+	}
+
+	// flexible members fill the remaining slots
+	flexible = append([]model.MemberInfo{}, flexible...)
+	sortByLevel(flexible)
+	for _, m := range flexible {
+		assign(lowestAverageParty(), m)
+	}
+	return parties
+}
+
+// raidPartyDraft is an auto formation proposal being edited before it is applied.
+// The version changes on every edit, so a stale message cannot apply a different proposal than it shows.
+type raidPartyDraft struct {
+	Version int
+	Parties []model.RaidPartyInfo
+}
+
+// drafts are kept in memory per raid info, a restart discards them
+var raidPartyDrafts = make(map[uint]raidPartyDraft)
+var raidPartyDraftVersion = 0
+var raidPartyDraftLock = &sync.Mutex{}
+
+func saveRaidPartyDraft(infoID uint, parties []model.RaidPartyInfo) raidPartyDraft {
+	raidPartyDraftLock.Lock()
+	defer raidPartyDraftLock.Unlock()
+	raidPartyDraftVersion++
+	draft := raidPartyDraft{Version: raidPartyDraftVersion, Parties: parties}
+	raidPartyDrafts[infoID] = draft
+	return draft
+}
+
+// getRaidPartyDraft returns a copy of the draft if it is still the given version.
+func getRaidPartyDraft(infoID uint, version string) (raidPartyDraft, bool) {
+	raidPartyDraftLock.Lock()
+	defer raidPartyDraftLock.Unlock()
+	draft, ok := raidPartyDrafts[infoID]
+	if !ok || strconv.Itoa(draft.Version) != version {
+		return raidPartyDraft{}, false
+	}
+
+	parties := make([]model.RaidPartyInfo, len(draft.Parties))
+	for idx, p := range draft.Parties {
+		parties[idx] = p
+		parties[idx].Members = slices.Clone(p.Members)
+	}
+	draft.Parties = parties
+	return draft, true
+}
+
+func assignPartyLeaders(parties []model.RaidPartyInfo) {
+	for _, p := range parties {
+		for idx := range p.Members {
+			p.Members[idx].Role = "파티원"
+			if idx == 0 {
+				p.Members[idx].Role = "파티장"
+			}
+		}
+	}
+}
+
+func findRaidInfo(infoID string) (model.RaidInfo, bool) {
+	var info model.RaidInfo
+	err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&info, infoID).Error
+	return info, err == nil
+}
+
+func raidPartyDraftExpired(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string) {
+	raidPartyFormationHandler(s, i, infoID, "자동 편성 제안이 만료되었거나 다른 운영진이 수정했습니다. 자동 편성을 다시 실행해주세요.")
+}
+
+func raidPartyAutoHandler(s *discordgo.Session, i *discordgo.InteractionCreate, infoID string) {
+	info, ok := findRaidInfo(infoID)
+	if !ok {
+		return
+	}
+
+	// get attend
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
+
+	proposal := proposeRaidParties(attends)
+	if len(proposal) == 0 {
+		raidPartyFormationHandler(s, i, infoID, "자동 편성할 참가자가 없습니다.")
+		return
+	}
+
+	raidPartyDraftView(s, i, info, saveRaidPartyDraft(info.ID, proposal), nil, "")
+}
+
+// raidPartyDraftView shows the draft with its edit controls, picked holds the orders of the two parties being edited.
+func raidPartyDraftView(s *discordgo.Session, i *discordgo.InteractionCreate, info model.RaidInfo, draft raidPartyDraft, picked []int, notice string) {
+	msg := ""
+	if notice != "" {
+		msg += notice + "\n\n"
+	}
+	msg += fmt.Sprintf("**[%s] %s (%d트라이) 자동 편성 제안**\n\n",
+		info.RaidSchedule.Raid.RaidName, info.RaidSchedule.StartTime.In(loc).Format("2006-01-02 15:04"), info.RaidSchedule.TryCount)
+	msg += renderRaidParties(draft.Parties, false)
+
+	var averages []string
+	for _, p := range draft.Parties {
+		if len(p.Members) == 0 {
+			continue
+		}
+		sum := 0
+		for _, m := range p.Members {
+			sum += m.Level
+		}
+		averages = append(averages, fmt.Sprintf("%d파티 %.1f", p.Order, float64(sum)/float64(len(p.Members))))
+	}
+	msg += fmt.Sprintf("파티별 평균 레벨: %s\n", strings.Join(averages, " / "))
+	msg += "적용 전에 파티 두 개를 골라 파티원을 옮기거나 서로 바꿀 수 있으며, 보이는 그대로 적용됩니다."
+
+	var components []discordgo.MessageComponent
+	if len(draft.Parties) >= 2 {
+		var partyOptions []discordgo.SelectMenuOption
+		for _, p := range draft.Parties {
+			if len(partyOptions) >= 25 {
+				break
+			}
+			partyOptions = append(partyOptions, discordgo.SelectMenuOption{
+				Label:   fmt.Sprintf("%d파티 (%s, %d명)", p.Order, p.PartyRole, len(p.Members)),
+				Value:   strconv.Itoa(p.Order),
+				Default: slices.Contains(picked, p.Order),
+			})
+		}
+		minValues := 2
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    fmt.Sprintf("admin-party-draft-pick_%d_%d", info.ID, draft.Version),
+					Placeholder: "수정할 파티 두 개 선택",
+					MinValues:   &minValues,
+					MaxValues:   2,
+					Options:     partyOptions,
+				},
+			},
+		})
+	}
+
+	if len(picked) == 2 {
+		var memberOptions []discordgo.SelectMenuOption
+		for _, p := range draft.Parties {
+			if !slices.Contains(picked, p.Order) {
+				continue
+			}
+			for _, m := range p.Members {
+				memberOptions = append(memberOptions, discordgo.SelectMenuOption{
+					Label: fmt.Sprintf("%d파티 / %s / %d / %s", p.Order, m.SubRoleName, m.Level, m.Nickname),
+					Value: m.Nickname,
+				})
+			}
+		}
+		if len(memberOptions) > 0 {
+			components = append(components, discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID:    fmt.Sprintf("admin-party-draft-move_%d_%d_%d_%d", info.ID, draft.Version, picked[0], picked[1]),
+						Placeholder: "한 명은 다른 파티로 이동, 두 명은 서로 교환",
+						MaxValues:   min(2, len(memberOptions)),
+						Options:     memberOptions,
+					},
+				},
+			})
+		}
+	}
+
+	components = append(components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "제안 적용",
+				Style:    discordgo.PrimaryButton,
+				CustomID: fmt.Sprintf("admin-party-auto-apply_%d_%d", info.ID, draft.Version),
+			},
+			discordgo.Button{
+				Label:    "다시 제안",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("admin-party-auto_%d", info.ID),
+			},
+			discordgo.Button{
+				Label:    "파티 목록으로 돌아가기",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("admin-info-party-formation_%d", info.ID),
+			},
+		},
+	})
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: components,
+		},
+	})
+}
+
+func raidPartyDraftPickHandler(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 3 {
+		return
+	}
+	info, ok := findRaidInfo(args[1])
+	if !ok {
+		return
+	}
+	draft, ok := getRaidPartyDraft(info.ID, args[2])
+	if !ok {
+		raidPartyDraftExpired(s, i, args[1])
+		return
+	}
+
+	var picked []int
+	for _, v := range i.MessageComponentData().Values {
+		if order, err := strconv.Atoi(v); err == nil {
+			picked = append(picked, order)
+		}
+	}
+	if len(picked) != 2 {
+		picked = nil
+	}
+	raidPartyDraftView(s, i, info, draft, picked, "")
+}
+
+// raidPartyDraftMoveHandler moves a selected member to the other picked party, or swaps two members of different parties.
+func raidPartyDraftMoveHandler(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 5 {
+		return
+	}
+	info, ok := findRaidInfo(args[1])
+	if !ok {
+		return
+	}
+	draft, ok := getRaidPartyDraft(info.ID, args[2])
+	if !ok {
+		raidPartyDraftExpired(s, i, args[1])
+		return
+	}
+	a, errA := strconv.Atoi(args[3])
+	b, errB := strconv.Atoi(args[4])
+	if errA != nil || errB != nil || a < 1 || b < 1 || a > len(draft.Parties) || b > len(draft.Parties) {
+		return
+	}
+	picked := []int{a, b}
+
+	// locate the selected members among the two parties
+	type position struct {
+		Party  int
+		Member int
+	}
+	var selected []position
+	for _, nickname := range i.MessageComponentData().Values {
+		for _, order := range picked {
+			p := draft.Parties[order-1]
+			if idx := slices.IndexFunc(p.Members, func(m model.RaidPartyMemberInfo) bool { return m.Nickname == nickname }); idx >= 0 {
+				selected = append(selected, position{Party: order - 1, Member: idx})
+				break
+			}
+		}
+	}
+
+	notice := ""
+	switch len(selected) {
+	case 1:
+		from := selected[0].Party
+		to := a - 1
+		if from == to {
+			to = b - 1
+		}
+		if len(draft.Parties[to].Members) >= maxPartyMemberCount {
+			notice = fmt.Sprintf("%d파티가 가득 차 있어 옮길 수 없습니다. 두 명을 골라 서로 바꿔주세요.", to+1)
+			break
+		}
+		m := draft.Parties[from].Members[selected[0].Member]
+		draft.Parties[from].Members = slices.Delete(draft.Parties[from].Members, selected[0].Member, selected[0].Member+1)
+		draft.Parties[to].Members = append(draft.Parties[to].Members, m)
+		notice = fmt.Sprintf("%s 님을 %d파티로 옮겼습니다.", m.Nickname, to+1)
+	case 2:
+		x, y := selected[0], selected[1]
+		if x.Party == y.Party {
+			notice = "서로 다른 파티의 두 명을 선택해주세요."
+			break
+		}
+		mx, my := draft.Parties[x.Party].Members[x.Member], draft.Parties[y.Party].Members[y.Member]
+		draft.Parties[x.Party].Members[x.Member], draft.Parties[y.Party].Members[y.Member] = my, mx
+		notice = fmt.Sprintf("%s 님과 %s 님을 서로 바꿨습니다.", mx.Nickname, my.Nickname)
+	default:
+		raidPartyDraftView(s, i, info, draft, picked, "")
+		return
+	}
+
+	assignPartyLeaders(draft.Parties)
+	raidPartyDraftView(s, i, info, saveRaidPartyDraft(info.ID, draft.Parties), picked, notice)
+}
+
+// raidPartyAutoApplyHandler saves the draft as shown, asking first when it would replace existing parties.
+func raidPartyAutoApplyHandler(s *discordgo.Session, i *discordgo.InteractionCreate, args []string, confirmed bool) {
+	if len(args) < 3 {
+		raidPartyDraftExpired(s, i, args[1])
+		return
+	}
+	info, ok := findRaidInfo(args[1])
+	if !ok {
+		return
+	}
+	draft, ok := getRaidPartyDraft(info.ID, args[2])
+	if !ok {
+		raidPartyDraftExpired(s, i, args[1])
+		return
+	}
+
+	existing := listRaidParties(info.ID)
+	if len(existing) > 0 && !confirmed {
+		members := 0
+		for _, p := range existing {
+			members += len(p.Members)
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("**기존 파티 %d개 (%d명)가 자동 편성 제안으로 대체됩니다.**\n직접 수정한 파티 구성도 모두 사라집니다. 계속하시겠습니까?\n\n**[현재 파티 구성]**\n%s",
+					len(existing), members, renderRaidParties(existing, false)),
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.Button{
+								Label:    "기존 파티 덮어쓰기",
+								Style:    discordgo.DangerButton,
+								CustomID: fmt.Sprintf("admin-party-auto-confirm_%d_%d", info.ID, draft.Version),
+							},
+							discordgo.Button{
+								Label:    "제안으로 돌아가기",
+								Style:    discordgo.SecondaryButton,
+								CustomID: fmt.Sprintf("admin-party-draft-view_%d_%d", info.ID, draft.Version),
+							},
+						},
+					},
+				},
+			},
+		})
+		return
+	}
+
+	// replace parties
+	for _, p := range existing {
+		rdb.Where("raid_party_info_id = ?", p.ID).Delete(&model.RaidPartyMemberInfo{})
+		rdb.Delete(&p)
+	}
+	order := 0
+	for _, p := range draft.Parties {
+		// parties emptied while editing are dropped
+		if len(p.Members) == 0 {
+			continue
+		}
+		order++
+		p.Order = order
+		p.RaidInfoID = info.ID
+		rdb.Create(&p)
+	}
+
+	raidPartyDraftLock.Lock()
+	delete(raidPartyDrafts, info.ID)
+	raidPartyDraftLock.Unlock()
+
+	notice := "자동 편성이 적용되었습니다. 필요하면 파티를 선택해 수정한 뒤 게시하세요."
+
+	// the draft is applied as shown, point out the members who left since it was proposed
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
+	var left []string
+	for _, p := range draft.Parties {
+		for _, m := range p.Members {
+			if !slices.ContainsFunc(attends, func(a model.RaidAttend) bool { return a.Nickname == m.Nickname }) {
+				left = append(left, m.Nickname)
+			}
+		}
+	}
+	if len(left) > 0 {
+		notice += fmt.Sprintf("\n제안 이후 참가를 취소한 길드원이 포함되어 있습니다: %s", strings.Join(left, ", "))
+	}
+	raidPartyFormationHandler(s, i, args[1], notice)
+}
+
+func raidPartyDraftViewHandler(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) {
+	if len(args) < 3 {
+		return
+	}
+	info, ok := findRaidInfo(args[1])
+	if !ok {
+		return
+	}
+	draft, ok := getRaidPartyDraft(info.ID, args[2])
+	if !ok {
+		raidPartyDraftExpired(s, i, args[1])
+		return
+	}
+	raidPartyDraftView(s, i, info, draft, nil, "")
+}
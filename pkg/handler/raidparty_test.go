@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/sokdak/eternity-bot/pkg/model"
+)
+
+func testAttend(nickname, mainRole, subRole string, level int, preference string) model.RaidAttend {
+	return model.RaidAttend{
+		MemberInfo: model.MemberInfo{
+			Nickname:     nickname,
+			MainRoleName: mainRole,
+			SubRoleName:  subRole,
+			Level:        level,
+			Mention:      "<@" + nickname + ">",
+		},
+		Preference: preference,
+	}
+}
+
+func TestProposeRaidParties(t *testing.T) {
+	var manyBackups []model.RaidAttend
+	for n := 0; n < 7; n++ {
+		manyBackups = append(manyBackups, testAttend(fmt.Sprintf("backup%d", n), "도적", "섀도어", 100+n, model.RaidAttendPreferenceBackup))
+	}
+
+	tests := []struct {
+		name      string
+		attends   []model.RaidAttend
+		wantRoles []string
+		wantSizes []int
+	}{
+		{
+			name: "no attendees",
+		},
+		{
+			name: "bishops spread and warriors in front",
+			attends: []model.RaidAttend{
+				testAttend("b1", "마법사", "비숍", 150, ""),
+				testAttend("b2", "마법사", "비숍", 140, ""),
+				testAttend("w1", "전사", "히어로", 150, ""),
+				testAttend("w2", "전사", "팔라딘", 140, ""),
+				testAttend("w3", "전사", "다크나이트", 130, ""),
+				testAttend("w4", "전사", "히어로", 120, ""),
+				testAttend("t1", "도적", "나이트로드", 135, ""),
+			},
+			wantRoles: []string{"전방", "메인딜"},
+			wantSizes: []int{4, 3},
+		},
+		{
+			name: "backups in their own parties",
+			attends: append([]model.RaidAttend{
+				testAttend("m1", "궁수", "신궁", 130, model.RaidAttendPreferenceMain),
+				testAttend("m2", "궁수", "보우마스터", 125, ""),
+			}, manyBackups...),
+			wantRoles: []string{"전방", "예비", "예비"},
+			wantSizes: []int{2, 6, 1},
+		},
+		{
+			name: "flexible fills the remaining slot",
+			attends: []model.RaidAttend{
+				testAttend("m1", "궁수", "신궁", 130, ""),
+				testAttend("m2", "궁수", "신궁", 128, ""),
+				testAttend("m3", "궁수", "신궁", 126, ""),
+				testAttend("m4", "궁수", "신궁", 124, ""),
+				testAttend("m5", "궁수", "신궁", 122, ""),
+				testAttend("f1", "도적", "섀도어", 150, model.RaidAttendPreferenceFlexible),
+			},
+			wantRoles: []string{"전방"},
+			wantSizes: []int{6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parties := proposeRaidParties(tt.attends)
+			if len(parties) != len(tt.wantRoles) {
+				t.Fatalf("got %d parties, want %d", len(parties), len(tt.wantRoles))
+			}
+			for idx, p := range parties {
+				if p.Order != idx+1 {
+					t.Errorf("party %d: got order %d", idx, p.Order)
+				}
+				if p.PartyRole != tt.wantRoles[idx] {
+					t.Errorf("party %d: got role %s, want %s", idx, p.PartyRole, tt.wantRoles[idx])
+				}
+				if len(p.Members) != tt.wantSizes[idx] {
+					t.Errorf("party %d: got %d members, want %d", idx, len(p.Members), tt.wantSizes[idx])
+				}
+				for k, m := range p.Members {
+					wantRole := "파티원"
+					if k == 0 {
+						wantRole = "파티장"
+					}
+					if m.Role != wantRole {
+						t.Errorf("party %d member %d: got role %s, want %s", idx, k, m.Role, wantRole)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProposeRaidPartiesSpreadsBishops(t *testing.T) {
+	var attends []model.RaidAttend
+	for n := 0; n < 3; n++ {
+		attends = append(attends, testAttend(fmt.Sprintf("b%d", n), "마법사", "비숍", 140+n, ""))
+	}
+	for n := 0; n < 12; n++ {
+		attends = append(attends, testAttend(fmt.Sprintf("a%d", n), "궁수", "신궁", 110+n, ""))
+	}
+
+	for idx, p := range proposeRaidParties(attends) {
+		bishops := 0
+		for _, m := range p.Members {
+			if m.SubRoleName == "비숍" {
+				bishops++
+			}
+		}
+		if bishops != 1 {
+			t.Errorf("party %d: got %d bishops, want 1", idx, bishops)
+		}
+	}
+}
+
+func TestRaidPartyDraftIsCopied(t *testing.T) {
+	proposal := proposeRaidParties([]model.RaidAttend{
+		testAttend("a", "궁수", "신궁", 120, ""),
+		testAttend("b", "궁수", "신궁", 110, ""),
+	})
+	draft := saveRaidPartyDraft(1, proposal)
+
+	edited, ok := getRaidPartyDraft(1, strconv.Itoa(draft.Version))
+	if !ok {
+		t.Fatal("draft not found")
+	}
+	edited.Parties[0].Members[0].Nickname = "changed"
+
+	again, _ := getRaidPartyDraft(1, strconv.Itoa(draft.Version))
+	if again.Parties[0].Members[0].Nickname == "changed" {
+		t.Error("editing a draft copy changed the stored draft")
+	}
+
+	newer := saveRaidPartyDraft(1, edited.Parties)
+	if _, ok := getRaidPartyDraft(1, strconv.Itoa(draft.Version)); ok {
+		t.Error("an older version of the draft is still returned")
+	}
+	if _, ok := getRaidPartyDraft(1, strconv.Itoa(newer.Version)); !ok {
+		t.Error("the latest version of the draft is not returned")
+	}
+}
@@ -0,0 +1,369 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/discord"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"slices"
+)
+
+// raidActionScopes maps raid-scoped actions and modals to the kind of id they carry,
+// either as the custom id argument or as the selected value.
+var raidActionScopes = map[string]string{
+	"admin-add-schedule-select-raid":                "raid",
+	"admin-template-add-select-raid":                "raid",
+	"admin-manager-add":                             "raid",
+	"admin-manager-remove":                          "raid",
+	"admin-manager-claim":                           "raid",
+	"add-raid-schedule-modal":                       "raid",
+	"add-raid-template-modal":                       "raid",
+	"add-raid-manager-modal":                        "raid",
+	"admin-edit-schedule-select-schedule":           "schedule",
+	"admin-remove-schedule-select-schedule":         "schedule",
+	"admin-edit-attendance":                         "schedule",
+	"admin-edit-attendance-select-schedule":         "schedule",
+	"admin-edit-attendance-remove":                  "schedule",
+	"admin-edit-attendance-add":                     "schedule",
+	"admin-edit-attendance-specout":                 "schedule",
+	"admin-manage-info-select-schedule":             "schedule",
+	"edit-raid-schedule-modal":                      "schedule",
+	"admin-add-attendee-modal":                      "schedule",
+	"admin-edit-attendance-remove-select-attendee":  "attend",
+	"admin-edit-attendance-specout-select-attendee": "attend",
+	"admin-info-record-entrance":                    "info",
+	"admin-info-record-start":                       "info",
+	"admin-info-record-end":                         "info",
+	"admin-info-attendance":                         "info",
+	"admin-info-attendance-refresh":                 "info",
+	"admin-info-party-formation":                    "info",
+	"admin-info-view":                               "info",
+	"admin-info-distribution":                       "info",
+	"admin-info-distribution-select-rule":           "info",
+	"admin-info-distribution-calc":                  "info",
+	"admin-info-loot":                               "info",
+	"admin-distribution-add-rule":                   "info",
+	"admin-party-select-party":                      "info",
+	"admin-party-publish":                           "info",
+	"admin-party-auto":                              "info",
+	"admin-party-auto-apply":                        "info",
+	"admin-party-auto-confirm":                      "info",
+	"admin-party-draft-view":                        "info",
+	"admin-party-draft-pick":                        "info",
+	"admin-party-draft-move":                        "info",
+	"add-distribution-rule-modal":                   "info",
+	"raid-loot-modal":                               "info",
+	"admin-party-set-members":                       "party",
+	"admin-party-edit-page":                         "party",
+	"admin-party-set-role":                          "party",
+	"admin-party-remove":                            "party",
+	"admin-template-select":                         "template",
+	"admin-template-edit":                           "template",
+	"admin-template-toggle":                         "template",
+	"admin-template-remove":                         "template",
+	"edit-raid-template-modal":                      "template",
+}
+
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// isRaidAdmin reports whether the user has the guild-admin role, who can manage every raid.
+func isRaidAdmin(i *discordgo.InteractionCreate) bool {
+	if i.Member == nil {
+		return false
+	}
+	return slices.Contains(i.Member.Roles, environment.DiscordGuildRaidAdminRoleID) ||
+		i.Member.Permissions&discordgo.PermissionAdministrator != 0
+}
+
+func canManageRaid(i *discordgo.InteractionCreate, raid model.Raid) bool {
+	if isRaidAdmin(i) {
+		return true
+	}
+
+	userID := interactionUserID(i)
+	if userID == "" {
+		return false
+	}
+	if raid.Manager != "" && userIDFromMention(raid.Manager) == userID {
+		return true
+	}
+
+	var count int64
+	rdb.Model(&model.RaidCoManager{}).Where("raid_id = ? AND mention = ?", raid.ID, fmt.Sprintf("<@%s>", userID)).Count(&count)
+	return count > 0
+}
+
+// raidForScope finds the raid that the id of the given kind belongs to.
+func raidForScope(kind string, id string) (model.Raid, error) {
+	switch kind {
+	case "raid":
+		var raid model.Raid
+		err := rdb.First(&raid, id).Error
+		return raid, err
+	case "schedule":
+		var schedule model.RaidSchedule
+		err := rdb.Preload("Raid").First(&schedule, id).Error
+		return schedule.Raid, err
+	case "attend":
+		var attend model.RaidAttend
+		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&attend, id).Error
+		return attend.RaidSchedule.Raid, err
+	case "info":
+		var info model.RaidInfo
+		err := rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").First(&info, id).Error
+		return info.RaidSchedule.Raid, err
+	case "party":
+		var party model.RaidPartyInfo
+		err := rdb.Preload("RaidInfo").Preload("RaidInfo.RaidSchedule").Preload("RaidInfo.RaidSchedule.Raid").First(&party, id).Error
+		return party.RaidInfo.RaidSchedule.Raid, err
+	case "template":
+		var tpl model.RaidScheduleTemplate
+		err := rdb.Preload("Raid").First(&tpl, id).Error
+		return tpl.Raid, err
+	}
+	return model.Raid{}, fmt.Errorf("unknown scope: %s", kind)
+}
+
+// authorizeRaidAction checks that the user manages the raid the action belongs to,
+// and responds with an error if not. Actions that are not raid-scoped are always allowed.
+func authorizeRaidAction(s *discordgo.Session, i *discordgo.InteractionCreate, args []string) bool {
+	kind, ok := raidActionScopes[args[0]]
+	if !ok {
+		return true
+	}
+
+	var id string
+	if len(args) > 1 {
+		id = args[1]
+	} else if i.Type == discordgo.InteractionMessageComponent {
+		if values := i.MessageComponentData().Values; len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		return true
+	}
+
+	raid, err := raidForScope(kind, id)
+	if err != nil {
+		// let the handler deal with missing records
+		return true
+	}
+	if canManageRaid(i, raid) {
+		return true
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("레이드 '%s'의 관리 권한이 없습니다. 레이드 관리자나 길드 관리자에게 문의해주세요.", raid.RaidName),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	return false
+}
+
+func describeRaidManagers(raid model.Raid) string {
+	msg := "* 관리자: "
+	if raid.Manager == "" {
+		msg += "없음 (길드 관리자만 관리 가능)\n"
+	} else {
+		msg += raid.Manager + "\n"
+	}
+
+	var coManagers []model.RaidCoManager
+	rdb.Where("raid_id = ?", raid.ID).Find(&coManagers)
+	msg += "* 공동 관리자: "
+	if len(coManagers) == 0 {
+		msg += "없음\n"
+	}
+	for idx, c := range coManagers {
+		if idx > 0 {
+			msg += ", "
+		}
+		msg += c.Mention
+	}
+	if len(coManagers) > 0 {
+		msg += "\n"
+	}
+	return msg
+}
+
+func raidManagerListHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// get existing raids
+	var raids []model.Raid
+	rdb.Find(&raids)
+
+	// list raids
+	raidSelectionMap := make(map[string]string)
+	for _, r := range raids {
+		raidSelectionMap[r.RaidName] = "admin-manager-select-raid_" + fmt.Sprintf("%d", r.ID)
+	}
+	raidSelectionMap["처음으로 돌아가기"] = "admin-landing-page"
+
+	// send message
+	discord.SendInteractionWithButtons(s, i.Interaction, "관리자를 확인 할 레이드를 선택하세요.", raidSelectionMap, true)
+}
+
+func raidManagerHandler(s *discordgo.Session, i *discordgo.InteractionCreate, raidID string, notice string) {
+	// get raid
+	var raid model.Raid
+	err := rdb.First(&raid, raidID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	msg := ""
+	if notice != "" {
+		msg += notice + "\n\n"
+	}
+	msg += fmt.Sprintf("**[%s] 레이드 관리자**\n", raid.RaidName)
+	msg += describeRaidManagers(raid)
+	msg += "\n일정 수정, 참가자 관리, 레이드 기록은 관리자, 공동 관리자, 길드 관리자만 할 수 있습니다."
+
+	var components []discordgo.MessageComponent
+
+	var coManagers []model.RaidCoManager
+	rdb.Where("raid_id = ?", raid.ID).Find(&coManagers)
+	if len(coManagers) > 0 {
+		var selectOptions []discordgo.SelectMenuOption
+		for _, c := range coManagers {
+			selectOptions = append(selectOptions, discordgo.SelectMenuOption{
+				Label: c.Nickname,
+				Value: fmt.Sprintf("%d", c.ID),
+			})
+		}
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    fmt.Sprintf("admin-manager-remove_%d", raid.ID),
+					Placeholder: "해제할 공동 관리자 선택",
+					Options:     selectOptions,
+				},
+			},
+		})
+	}
+
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "공동 관리자 추가",
+			Style:    discordgo.PrimaryButton,
+			CustomID: fmt.Sprintf("admin-manager-add_%d", raid.ID),
+		},
+	}
+	if isRaidAdmin(i) {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "내가 관리자 맡기",
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("admin-manager-claim_%d", raid.ID),
+		})
+	}
+	buttons = append(buttons, discordgo.Button{
+		Label:    "레이드 목록으로 돌아가기",
+		Style:    discordgo.SecondaryButton,
+		CustomID: "admin-manager",
+	})
+	components = append(components, discordgo.ActionsRow{Components: buttons})
+
+	respType := discordgo.InteractionResponseUpdateMessage
+	if i.Type == discordgo.InteractionModalSubmit {
+		respType = discordgo.InteractionResponseChannelMessageWithSource
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: respType,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: components,
+		},
+	})
+}
+
+func raidManagerRemoveHandler(s *discordgo.Session, i *discordgo.InteractionCreate, raidID string) {
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		return
+	}
+
+	var coManager model.RaidCoManager
+	err := rdb.Where("raid_id = ?", raidID).First(&coManager, values[0]).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	rdb.Delete(&coManager)
+
+	raidManagerHandler(s, i, raidID, fmt.Sprintf("공동 관리자 '%s'가 해제되었습니다.", coManager.Nickname))
+}
+
+func raidManagerClaimHandler(s *discordgo.Session, i *discordgo.InteractionCreate, raidID string) {
+	if !isRaidAdmin(i) {
+		return
+	}
+
+	// get raid
+	var raid model.Raid
+	err := rdb.First(&raid, raidID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	raid.Manager = fmt.Sprintf("<@%s>", interactionUserID(i))
+	rdb.Save(&raid)
+
+	raidManagerHandler(s, i, raidID, "레이드 관리자가 변경되었습니다.")
+}
+
+func raidManagerModalHandler(s *discordgo.Session, i *discordgo.InteractionCreate, raidID string) {
+	modalData := i.ModalSubmitData().Components
+
+	var nickname string
+	for _, comp := range modalData {
+		if ar, ok := comp.(*discordgo.ActionsRow); ok {
+			if ti, ok := ar.Components[0].(*discordgo.TextInput); ok {
+				if ti.CustomID == "nickname" {
+					nickname = ti.Value
+				}
+			}
+		}
+	}
+
+	// get raid
+	var raid model.Raid
+	err := rdb.First(&raid, raidID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	// get member
+	memberMap := cache.ListAllMembersNicknameMap()
+	member, ok := memberMap[nickname]
+	if !ok {
+		raidManagerHandler(s, i, raidID, fmt.Sprintf("길드원 '%s'를 찾을 수 없습니다.", nickname))
+		return
+	}
+	m, err := GetMemberInfoFromMember(member)
+	if err != nil {
+		return
+	}
+
+	var count int64
+	rdb.Model(&model.RaidCoManager{}).Where("raid_id = ? AND mention = ?", raid.ID, m.Mention).Count(&count)
+	if count > 0 || m.Mention == raid.Manager {
+		raidManagerHandler(s, i, raidID, fmt.Sprintf("'%s'는 이미 관리자입니다.", nickname))
+		return
+	}
+
+	rdb.Create(&model.RaidCoManager{RaidID: raid.ID, MemberInfo: *m})
+	raidManagerHandler(s, i, raidID, fmt.Sprintf("공동 관리자 '%s'가 추가되었습니다.", nickname))
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// usualAttendRecentSchedules is how many past schedules of the same raid are checked for usual attendees.
+	usualAttendRecentSchedules = 4
+	// usualAttendMinCount is how many of those schedules a member must have attended to count as a usual attendee.
+	usualAttendMinCount = 2
+)
+
+// reminder offsets are parsed once by RaidInit
+var raidReminderOffsets, raidSubscriptionReminderOffsets []time.Duration
+
+// parseReminderOffsets parses offsets such as "1h,10m" and returns them in ascending order.
+// Invalid items are skipped and reported in the error along with the valid offsets.
+func parseReminderOffsets(text string) ([]time.Duration, error) {
+	var offsets []time.Duration
+	var invalid []string
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		d, err := time.ParseDuration(item)
+		if err != nil || d <= 0 {
+			invalid = append(invalid, item)
+			continue
+		}
+		offsets = append(offsets, d)
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		return offsets[i] < offsets[j]
+	})
+	if len(invalid) > 0 {
+		return offsets, fmt.Errorf("invalid raid reminder offsets: %s", strings.Join(invalid, ", "))
+	}
+	return offsets, nil
+}
+
+func loadRaidReminderOffsets() {
+	var err error
+	raidReminderOffsets, err = parseReminderOffsets(environment.RaidReminderOffsets)
+	if err != nil {
+		fmt.Println("RAID_REMINDER_OFFSETS:", err)
+	}
+	raidSubscriptionReminderOffsets, err = parseReminderOffsets(environment.RaidSubscriptionReminderOffset)
+	if err != nil {
+		fmt.Println("RAID_SUBSCRIPTION_REMINDER_OFFSET:", err)
+	}
+}
+
+// resetRaidReminders re-arms the reminders whose timing changed with the schedule edit.
+func resetRaidReminders(before, after model.RaidSchedule) {
+	if !before.StartTime.Equal(after.StartTime) {
+		rdb.Unscoped().Where("raid_schedule_id = ? AND kind LIKE ?", after.ID, "start-%").Delete(&model.RaidReminder{})
+	}
+	if !before.SubscriptionEndTime.Equal(after.SubscriptionEndTime) {
+		rdb.Unscoped().Where("raid_schedule_id = ? AND kind = ?", after.ID, "subscription").Delete(&model.RaidReminder{})
+	}
+}
+
+func formatReminderOffset(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d시간", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d분", int(d.Minutes()))
+}
+
+func raidReminderSent(scheduleID uint, kind string) bool {
+	var count int64
+	rdb.Model(&model.RaidReminder{}).Where("raid_schedule_id = ? AND kind = ?", scheduleID, kind).Count(&count)
+	return count > 0
+}
+
+func markRaidReminderSent(scheduleID uint, kind string) {
+	if raidReminderSent(scheduleID, kind) {
+		return
+	}
+	rdb.Create(&model.RaidReminder{RaidScheduleID: scheduleID, Kind: kind})
+}
+
+// RaidReminderRefresh sends start reminders to attendees and closing reminders to usual attendees who haven't signed up.
+func RaidReminderRefresh(dg *discordgo.Session) error {
+	now := time.Now()
+
+	if offsets := raidReminderOffsets; len(offsets) > 0 {
+		var schedules []model.RaidSchedule
+		err := rdb.Preload("Raid").
+			Where("start_time > ? AND start_time <= ?", now.UTC(), now.Add(offsets[len(offsets)-1]).UTC()).
+			Find(&schedules).Error
+		if err != nil {
+			return fmt.Errorf("failed to get raid schedules: %w", err)
+		}
+
+		for _, sc := range schedules {
+			sendRaidStartReminder(dg, sc, offsets, now)
+		}
+	}
+
+	if offsets := raidSubscriptionReminderOffsets; len(offsets) > 0 {
+		var schedules []model.RaidSchedule
+		err := rdb.Preload("Raid").
+			Where("subscription_end_time > ? AND subscription_end_time <= ?", now.UTC(), now.Add(offsets[0]).UTC()).
+			Find(&schedules).Error
+		if err != nil {
+			return fmt.Errorf("failed to get raid schedules: %w", err)
+		}
+
+		for _, sc := range schedules {
+			sendRaidSubscriptionReminder(dg, sc)
+		}
+	}
+	return nil
+}
+
+// sendRaidStartReminder sends the reminder for the closest offset that has been reached.
+// Larger offsets are marked as sent too, so a restart never sends several reminders at once.
+func sendRaidStartReminder(dg *discordgo.Session, sc model.RaidSchedule, offsets []time.Duration, now time.Time) {
+	left := sc.StartTime.Sub(now)
+
+	idx := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= left })
+	if idx == len(offsets) {
+		return
+	}
+	kind := fmt.Sprintf("start-%s", offsets[idx])
+	if raidReminderSent(sc.ID, kind) {
+		return
+	}
+	for _, o := range offsets[idx:] {
+		markRaidReminderSent(sc.ID, fmt.Sprintf("start-%s", o))
+	}
+
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", sc.ID, false, false).Find(&attends)
+	if len(attends) == 0 {
+		return
+	}
+
+	startTime := sc.StartTime.In(loc).Format("2006-01-02 15:04")
+	msg := fmt.Sprintf("[%s] %s (%d트라이) 레이드 시작 %s 전입니다.", sc.Raid.RaidName, startTime, sc.TryCount, formatReminderOffset(offsets[idx]))
+
+	// send dm to each attendee
+	for _, a := range attends {
+		sendMessage(dg, userIDFromMention(a.Mention), msg)
+	}
+
+	// ping schedule role under the subscription message
+	mention := fmt.Sprintf("<@&%s>", sc.RoleID)
+	if sc.RoleID == "" {
+		var mentions []string
+		for _, a := range attends {
+			mentions = append(mentions, a.Mention)
+		}
+		mention = strings.Join(mentions, " ")
+	}
+	sendRaidScheduleReply(dg, sc, fmt.Sprintf("%s\n%s", mention, msg))
+}
+
+// sendRaidSubscriptionReminder notifies usual attendees who haven't signed up before the subscription closes.
+func sendRaidSubscriptionReminder(dg *discordgo.Session, sc model.RaidSchedule) {
+	if raidReminderSent(sc.ID, "subscription") {
+		return
+	}
+	markRaidReminderSent(sc.ID, "subscription")
+
+	// get members who already signed up
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ?", sc.ID, false).Find(&attends)
+	signedUp := make(map[string]bool)
+	for _, a := range attends {
+		signedUp[a.Mention] = true
+	}
+
+	msg := fmt.Sprintf("[%s] %s (%d트라이) 레이드 참가 신청이 %s에 마감됩니다. 참가하시려면 레이드 신청 채널에서 신청해주세요.",
+		sc.Raid.RaidName, sc.StartTime.In(loc).Format("2006-01-02 15:04"), sc.TryCount, sc.SubscriptionEndTime.In(loc).Format("01-02 15:04"))
+	for _, mention := range listUsualAttendees(sc) {
+		if signedUp[mention] {
+			continue
+		}
+		userID := userIDFromMention(mention)
+		if cache.GetGuildMember(userID) == nil {
+			continue
+		}
+		sendMessage(dg, userID, msg)
+	}
+}
+
+// listUsualAttendees returns the mentions of members who attended most of the recent schedules of the same raid.
+func listUsualAttendees(sc model.RaidSchedule) []string {
+	var recent []model.RaidSchedule
+	rdb.Where("raid_id = ? AND start_time < ? AND id <> ?", sc.RaidID, time.Now().UTC(), sc.ID).
+		Order("start_time desc").Limit(usualAttendRecentSchedules).Find(&recent)
+	if len(recent) == 0 {
+		return nil
+	}
+
+	var scheduleIDs []uint
+	for _, r := range recent {
+		scheduleIDs = append(scheduleIDs, r.ID)
+	}
+
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id IN ? AND canceled = ? AND waitlisted = ?", scheduleIDs, false, false).Find(&attends)
+
+	// count each schedule only once per member
+	counted := make(map[string]map[uint]bool)
+	for _, a := range attends {
+		if counted[a.Mention] == nil {
+			counted[a.Mention] = make(map[uint]bool)
+		}
+		counted[a.Mention][a.RaidScheduleID] = true
+	}
+
+	var usual []string
+	for mention, schedules := range counted {
+		if len(schedules) >= usualAttendMinCount {
+			usual = append(usual, mention)
+		}
+	}
+	sort.Strings(usual)
+	return usual
+}
+
+func sendRaidScheduleReply(dg *discordgo.Session, sc model.RaidSchedule, msg string) {
+	send := &discordgo.MessageSend{Content: msg}
+	if sc.MessageID != "" {
+		send.Reference = &discordgo.MessageReference{
+			MessageID: sc.MessageID,
+			ChannelID: environment.DiscordGuildRaidSubscriptionChannelID,
+		}
+	}
+	if _, err := dg.ChannelMessageSendComplex(environment.DiscordGuildRaidSubscriptionChannelID, send); err != nil {
+		fmt.Println("failed to send raid schedule reply:", err)
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReminderOffsets(t *testing.T) {
+	got, err := parseReminderOffsets(" 1h, 30m ,10m,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{10 * time.Minute, 30 * time.Minute, time.Hour}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got, err = parseReminderOffsets("")
+	if err != nil || len(got) != 0 {
+		t.Errorf("empty text: got %v, %v, want no offsets", got, err)
+	}
+}
+
+func TestParseReminderOffsetsKeepsValidItems(t *testing.T) {
+	got, err := parseReminderOffsets("10m,soon,-5m,0s")
+	if err == nil {
+		t.Fatal("expected an error for the invalid items")
+	}
+	for _, item := range []string{"soon", "-5m", "0s"} {
+		if !strings.Contains(err.Error(), item) {
+			t.Errorf("error %q does not mention %q", err, item)
+		}
+	}
+	if want := []time.Duration{10 * time.Minute}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFormatReminderOffset(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		10 * time.Minute: "10분",
+		time.Hour:        "1시간",
+		24 * time.Hour:   "24시간",
+		90 * time.Minute: "90분",
+	} {
+		if got := formatReminderOffset(d); got != want {
+			t.Errorf("formatReminderOffset(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// raidReportRecentRuns is how many recent runs the per raid type report covers.
+	raidReportRecentRuns = 10
+	// raidReportFastestRuns is how many of the fastest runs are shown with their composition.
+	raidReportFastestRuns = 3
+)
+
+// raidReportTypes are the known raid types followed by otherRaidType for raids of unknown type.
+var raidReportTypes = append(slices.Clone(raidTypeOrder), otherRaidType)
+
+type raidRun struct {
+	Info        model.RaidInfo
+	Delay       time.Duration
+	Clear       time.Duration
+	AttendCount int
+	Composition string
+}
+
+// listRaidRuns returns the finished runs of the raid type started in [since, until), oldest first.
+func listRaidRuns(raidType string, since, until time.Time) []raidRun {
+	var infos []model.RaidInfo
+	rdb.Preload("RaidSchedule").Preload("RaidSchedule.Raid").
+		Where("start_time >= ? AND start_time < ?", since.UTC(), until.UTC()).
+		Order("start_time asc").Find(&infos)
+
+	var runs []raidRun
+	for _, info := range infos {
+		if info.StartTime.IsZero() || info.EndTime.IsZero() || !info.EndTime.After(info.StartTime) {
+			continue
+		}
+		if raidReportType(info.RaidSchedule.Raid) != raidType {
+			continue
+		}
+
+		run := raidRun{Info: info, Clear: info.EndTime.Sub(info.StartTime)}
+		if !info.EntranceTime.IsZero() && info.StartTime.After(info.EntranceTime) {
+			run.Delay = info.StartTime.Sub(info.EntranceTime)
+		}
+		run.AttendCount, run.Composition = describeRaidRunComposition(info)
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// describeRaidRunComposition counts members by main role, from the parties if formed, otherwise from the attendees.
+func describeRaidRunComposition(info model.RaidInfo) (int, string) {
+	var members []model.MemberInfo
+	for _, p := range listRaidParties(info.ID) {
+		for _, m := range p.Members {
+			members = append(members, m.MemberInfo)
+		}
+	}
+	if len(members) == 0 {
+		var attends []model.RaidAttend
+		rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", info.RaidScheduleID, false, false).Find(&attends)
+		for _, a := range attends {
+			members = append(members, a.MemberInfo)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, m := range members {
+		counts[m.MainRoleName]++
+	}
+	var roles []string
+	for r := range counts {
+		roles = append(roles, r)
+	}
+	slices.Sort(roles)
+
+	var parts []string
+	for _, r := range roles {
+		parts = append(parts, fmt.Sprintf("%s %d", r, counts[r]))
+	}
+	return len(members), strings.Join(parts, " / ")
+}
+
+func formatRaidDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("%d시간 %d분", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%d분 %02d초", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func averageDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
+
+// renderRaidTypeReport renders the delay, clear time, trend and fastest runs of the recent runs of the raid type.
+func renderRaidTypeReport(raidType string) string {
+	runs := listRaidRuns(raidType, time.Time{}, time.Now())
+	if len(runs) > raidReportRecentRuns {
+		runs = runs[len(runs)-raidReportRecentRuns:]
+	}
+
+	msg := fmt.Sprintf("**[%s 레이드 리포트]** 최근 %d회\n", raidType, len(runs))
+	if len(runs) == 0 {
+		return msg + "시작/종료가 기록된 레이드가 없습니다.\n"
+	}
+
+	var delays, clears []time.Duration
+	var trend []string
+	for _, r := range runs {
+		if r.Delay > 0 {
+			delays = append(delays, r.Delay)
+		}
+		clears = append(clears, r.Clear)
+		trend = append(trend, formatRaidDuration(r.Clear))
+	}
+
+	msg += fmt.Sprintf("* 평균 입장~시작 대기: %s\n", formatRaidDuration(averageDuration(delays)))
+	msg += fmt.Sprintf("* 평균 클리어 시간: %s\n", formatRaidDuration(averageDuration(clears)))
+	msg += fmt.Sprintf("* 클리어 시간 추이: %s\n", strings.Join(trend, " → "))
+	if len(clears) >= 2 {
+		half := len(clears) / 2
+		older, newer := averageDuration(clears[:half]), averageDuration(clears[half:])
+		if newer < older {
+			msg += fmt.Sprintf("  (최근 평균 %s 단축)\n", formatRaidDuration(older-newer))
+		} else if newer > older {
+			msg += fmt.Sprintf("  (최근 평균 %s 증가)\n", formatRaidDuration(newer-older))
+		}
+	}
+
+	fastest := make([]raidRun, len(runs))
+	copy(fastest, runs)
+	sort.SliceStable(fastest, func(i, j int) bool {
+		return fastest[i].Clear < fastest[j].Clear
+	})
+	if len(fastest) > raidReportFastestRuns {
+		fastest = fastest[:raidReportFastestRuns]
+	}
+
+	msg += "\n**최단 클리어**\n"
+	for idx, r := range fastest {
+		msg += fmt.Sprintf("%d. %s (%d트라이) %s - %d명 (%s)\n", idx+1,
+			r.Info.RaidSchedule.StartTime.In(loc).Format("2006-01-02"), r.Info.RaidSchedule.TryCount,
+			formatRaidDuration(r.Clear), r.AttendCount, r.Composition)
+	}
+	return msg
+}
+
+func raidReportHandler(s *discordgo.Session, i *discordgo.InteractionCreate, raidType string) {
+	msg := "리포트를 볼 레이드 타입을 선택하세요."
+	if raidType != "" {
+		msg = renderRaidTypeReport(raidType)
+	}
+
+	var buttons []discordgo.MessageComponent
+	for _, rt := range raidReportTypes {
+		style := discordgo.SecondaryButton
+		if rt == raidType {
+			style = discordgo.PrimaryButton
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    rt,
+			Style:    style,
+			CustomID: "admin-report_" + rt,
+		})
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: buttons,
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "처음으로 돌아가기",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "admin-landing-page",
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+// RaidWeeklyReport posts the summary of last week's runs to the raid info channel once a week, on Monday morning.
+func RaidWeeklyReport(dg *discordgo.Session) error {
+	now := time.Now().In(loc)
+	if now.Weekday() != time.Monday || now.Hour() < 9 {
+		return nil
+	}
+
+	until := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	since := until.AddDate(0, 0, -7)
+	week := since.Format("2006-01-02")
+
+	var count int64
+	rdb.Model(&model.RaidReportPost{}).Where("week = ?", week).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("**[주간 레이드 리포트] %s ~ %s**\n\n", since.Format("01월 02일"), until.AddDate(0, 0, -1).Format("01월 02일"))
+	total := 0
+	for _, rt := range raidReportTypes {
+		runs := listRaidRuns(rt, since, until)
+		if len(runs) == 0 {
+			continue
+		}
+		total += len(runs)
+
+		var delays, clears []time.Duration
+		fastest := runs[0]
+		for _, r := range runs {
+			if r.Delay > 0 {
+				delays = append(delays, r.Delay)
+			}
+			clears = append(clears, r.Clear)
+			if r.Clear < fastest.Clear {
+				fastest = r
+			}
+		}
+
+		msg += fmt.Sprintf("**%s** %d회\n", rt, len(runs))
+		msg += fmt.Sprintf("* 평균 입장~시작 대기: %s\n", formatRaidDuration(averageDuration(delays)))
+		msg += fmt.Sprintf("* 평균 클리어 시간: %s\n", formatRaidDuration(averageDuration(clears)))
+		msg += fmt.Sprintf("* 최단 클리어: %s (%d트라이) %s - %d명 (%s)\n\n",
+			fastest.Info.RaidSchedule.StartTime.In(loc).Format("01월 02일"), fastest.Info.RaidSchedule.TryCount,
+			formatRaidDuration(fastest.Clear), fastest.AttendCount, fastest.Composition)
+	}
+	if total == 0 {
+		msg += "지난주에 기록된 레이드가 없습니다.\n"
+	}
+
+	if err := sendSplitMessage(dg, environment.DiscordGuildRaidInfoChannelID, msg); err != nil {
+		return fmt.Errorf("failed to send weekly raid report: %w", err)
+	}
+	rdb.Create(&model.RaidReportPost{Week: week})
+	return nil
+}
@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/discord"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// raidAttendPreferenceKeys maps the custom id keys of the sign-up preference buttons to the preferences.
+var raidAttendPreferenceKeys = map[string]string{
+	"main":   model.RaidAttendPreferenceMain,
+	"flex":   model.RaidAttendPreferenceFlexible,
+	"backup": model.RaidAttendPreferenceBackup,
+}
+
+var raidAttendPreferenceKeyOrder = []string{"main", "flex", "backup"}
+
+var raidAttendPreferenceDescriptions = map[string]string{
+	"main":   "주력 캐릭터로 참가",
+	"flex":   "부캐나 다른 직업으로 바꿔서 참가 가능",
+	"backup": "자리가 비면 참가하는 예비 인원",
+}
+
+func attendPreference(a model.RaidAttend) string {
+	if a.Preference == "" {
+		return model.RaidAttendPreferenceMain
+	}
+	return a.Preference
+}
+
+// describeAttendPreference returns e.g. " (유동, 부캐 비숍 가능)", or empty for main attendees without a note.
+func describeAttendPreference(a model.RaidAttend) string {
+	var parts []string
+	if pref := attendPreference(a); pref != model.RaidAttendPreferenceMain {
+		parts = append(parts, pref)
+	}
+	if a.Note != "" {
+		parts = append(parts, a.Note)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(parts, ", "))
+}
+
+func noteSuffix(a model.RaidAttend) string {
+	if a.Note == "" {
+		return ""
+	}
+	return " - " + a.Note
+}
+
+// raidSubscriptionComponents are the sign-up buttons attached to the subscription message of the schedule.
+func raidSubscriptionComponents(scheduleID uint) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "참가",
+					Style:    discordgo.PrimaryButton,
+					CustomID: fmt.Sprintf("raid-join_%d", scheduleID),
+				},
+				discordgo.Button{
+					Label:    "취소",
+					Style:    discordgo.DangerButton,
+					CustomID: fmt.Sprintf("raid-leave_%d", scheduleID),
+				},
+			},
+		},
+	}
+}
+
+// checkRaidJoin validates that the member can sign up for the schedule.
+// It returns the message to show to the member when not.
+func checkRaidJoin(schedule model.RaidSchedule, m model.MemberInfo) (string, bool) {
+	desc := fmt.Sprintf("[%s] %s (%d 트라이)", schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount)
+
+	// check subscription end time
+	if !schedule.SubscriptionEndTime.After(time.Now()) {
+		return fmt.Sprintf("%s 참가신청 기한이 마감되었습니다. 참가를 원하시면 공대장에게 문의해 주세요.", desc), false
+	}
+
+	// check level requirement
+	if rt, ok := getRaidType(schedule.Raid); ok && m.Level < rt.MinLevel {
+		return fmt.Sprintf("%s 는 최소 레벨 %d 이상만 참가할 수 있습니다. (현재 레벨 %d)\n닉네임의 레벨이 최신이 아니라면 닉네임을 수정한 뒤 다시 신청해주세요.",
+			desc, rt.MinLevel, m.Level), false
+	}
+
+	// check if already attended, with any character
+	var attend model.RaidAttend
+	err := rdb.Where("mention = ? AND raid_schedule_id = ?", m.Mention, schedule.ID).First(&attend).Error
+	if err == nil {
+		return fmt.Sprintf("%s 에 이미 참가하고 있습니다.", desc), false
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "참가 신청 중 오류가 발생했습니다.", false
+	}
+
+	// check conflicts with joined schedules
+	if conflicts := findMemberConflicts(schedule, m.Mention); len(conflicts) > 0 {
+		return fmt.Sprintf("%s 는 이미 참가중인 아래 일정과 시간이 겹쳐 신청할 수 없습니다.\n%s",
+			desc, describeRaidScheduleConflicts(conflicts)), false
+	}
+	return "", true
+}
+
+// joinRaidSchedule validates and signs the member up for the schedule with the preference and note.
+// It returns the message to show to the member and whether the sign-up was made.
+func joinRaidSchedule(schedule model.RaidSchedule, m model.MemberInfo, preference string, note string) (string, bool) {
+	// the checks and the insert see the same sign-ups
+	defer lockRaidSchedule(schedule.ID)()
+
+	if content, ok := checkRaidJoin(schedule, m); !ok {
+		return content, false
+	}
+	desc := fmt.Sprintf("[%s] %s (%d 트라이)", schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount)
+
+	// check capacity
+	var attends []model.RaidAttend
+	rdb.Where("raid_schedule_id = ? AND canceled = ? AND waitlisted = ?", schedule.ID, false, false).Find(&attends)
+
+	// create new attend
+	newAttend := model.RaidAttend{
+		MemberInfo:     m,
+		RaidScheduleID: schedule.ID,
+		Canceled:       false,
+		Preference:     preference,
+		Note:           note,
+	}
+	newAttend.Waitlisted = !canJoinRaidSchedule(schedule, newAttend, attends)
+	rdb.Create(&newAttend)
+	recordRaidLedger(schedule, m, model.RaidLedgerSignedUp, true)
+
+	if newAttend.Waitlisted {
+		return fmt.Sprintf("%s 정원이 가득 차 대기 %d번으로 신청되었습니다.\n자리가 나면 자동으로 참가가 확정되며 DM으로 알려드립니다.",
+			desc, waitlistPosition(newAttend)), true
+	}
+	return fmt.Sprintf("%s %s(으)로 참가 신청이 완료되었습니다.", desc, preference), true
+}
+
+// raidAttendPreferenceComponents are the buttons to choose the sign-up preference for the schedule with the character.
+func raidAttendPreferenceComponents(scheduleID uint, characterID string) []discordgo.MessageComponent {
+	var buttons []discordgo.MessageComponent
+	for _, key := range raidAttendPreferenceKeyOrder {
+		style := discordgo.SecondaryButton
+		if key == "main" {
+			style = discordgo.PrimaryButton
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    raidAttendPreferenceKeys[key],
+			Style:    style,
+			CustomID: fmt.Sprintf("raid-join-pref_%d_%s_%s", scheduleID, key, characterID),
+		})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+func describeRaidAttendPreferenceChoices(schedule model.RaidSchedule, m model.MemberInfo) string {
+	msg := fmt.Sprintf("[%s] %s (%d 트라이) %s 캐릭터의 참가 방식을 선택하세요. 다음 단계에서 메모를 남길 수 있습니다.\n",
+		schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount, m.Nickname)
+	for _, key := range raidAttendPreferenceKeyOrder {
+		msg += fmt.Sprintf("* %s: %s\n", raidAttendPreferenceKeys[key], raidAttendPreferenceDescriptions[key])
+	}
+	return msg
+}
+
+// raidJoinPrompt returns the next sign-up step for the schedule.
+// Members with several registered characters choose the character first, then the preference.
+// characterID "" means not chosen yet, "0" means the Discord nickname.
+func raidJoinPrompt(schedule model.RaidSchedule, member *discordgo.Member, characterID string) (string, []discordgo.MessageComponent) {
+	if characterID == "" {
+		characters := listApprovedCharacters(member.User.ID)
+		switch len(characters) {
+		case 0:
+			characterID = "0"
+		case 1:
+			characterID = strconv.Itoa(int(characters[0].ID))
+		default:
+			var options []discordgo.SelectMenuOption
+			for _, c := range characters {
+				if len(options) >= 25 {
+					break
+				}
+				option := discordgo.SelectMenuOption{
+					Label: describeCharacter(c),
+					Value: strconv.Itoa(int(c.ID)),
+				}
+				if rt, ok := getRaidType(schedule.Raid); ok && c.Level < rt.MinLevel {
+					option.Description = fmt.Sprintf("최소 레벨 %d 미달", rt.MinLevel)
+				}
+				options = append(options, option)
+			}
+			return fmt.Sprintf("[%s] %s (%d 트라이) 참가할 캐릭터를 선택하세요.",
+					schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount),
+				[]discordgo.MessageComponent{
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.SelectMenu{
+								CustomID:    fmt.Sprintf("raid-join-char_%d", schedule.ID),
+								Placeholder: "캐릭터 선택",
+								Options:     options,
+							},
+						},
+					},
+				}
+		}
+	}
+
+	m, err := getCharacterMemberInfo(member, characterID)
+	if err != nil {
+		return "닉네임이나 직업 역할을 확인할 수 없습니다. /캐릭터 명령어로 캐릭터를 등록하거나 닉네임과 역할을 확인해주세요.", nil
+	}
+	if content, ok := checkRaidJoin(schedule, *m); !ok {
+		return content, nil
+	}
+	return describeRaidAttendPreferenceChoices(schedule, *m), raidAttendPreferenceComponents(schedule.ID, characterID)
+}
+
+// raidAttendBackComponents appends the button back to the dm sign-up menu.
+func raidAttendBackComponents(components []discordgo.MessageComponent) []discordgo.MessageComponent {
+	return append(components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "참가 신청으로 돌아가기",
+				Style:    discordgo.PrimaryButton,
+				CustomID: "user-attend-schedule",
+			},
+		},
+	})
+}
+
+// raidJoinCharacterHandler continues the sign-up with the character chosen from the select.
+func raidJoinCharacterHandler(s *discordgo.Session, i *discordgo.InteractionCreate, scheduleID string, characterID string) {
+	memberInfo := cache.GetGuildMember(interactionUserID(i))
+	if memberInfo == nil {
+		respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+		return
+	}
+
+	var schedule model.RaidSchedule
+	err := rdb.Preload("Raid").First(&schedule, scheduleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondEphemeral(s, i, "레이드 일정을 찾을 수 없습니다.")
+		return
+	}
+
+	content, components := raidJoinPrompt(schedule, memberInfo, characterID)
+	if i.GuildID == "" {
+		components = raidAttendBackComponents(components)
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: components,
+		},
+	})
+}
+
+// raidAttendPreferenceHandler opens the note modal for the chosen preference.
+func raidAttendPreferenceHandler(s *discordgo.Session, i *discordgo.InteractionCreate, scheduleID string, key string, characterID string) {
+	if _, ok := raidAttendPreferenceKeys[key]; !ok {
+		return
+	}
+	discord.SendRaidAttendNoteModal(s, i.Interaction, scheduleID, key, characterID)
+}
+
+// raidAttendModalHandler signs the member up with the character, preference and note from the modal.
+func raidAttendModalHandler(s *discordgo.Session, i *discordgo.InteractionCreate, scheduleID string, key string, characterID string) {
+	preference, ok := raidAttendPreferenceKeys[key]
+	if !ok {
+		return
+	}
+
+	var note string
+	for _, comp := range i.ModalSubmitData().Components {
+		if ar, ok := comp.(*discordgo.ActionsRow); ok {
+			if ti, ok := ar.Components[0].(*discordgo.TextInput); ok {
+				if ti.CustomID == "note" {
+					note = strings.TrimSpace(ti.Value)
+				}
+			}
+		}
+	}
+
+	// get member info
+	memberInfo := cache.GetGuildMember(interactionUserID(i))
+	if memberInfo == nil {
+		respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+		return
+	}
+	m, err := getCharacterMemberInfo(memberInfo, characterID)
+	if err != nil {
+		respondEphemeral(s, i, "캐릭터 정보를 확인할 수 없습니다.")
+		return
+	}
+
+	// get schedule
+	var schedule model.RaidSchedule
+	err = rdb.Preload("Raid").First(&schedule, scheduleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondEphemeral(s, i, "레이드 일정을 찾을 수 없습니다.")
+		return
+	}
+
+	content, _ := joinRaidSchedule(schedule, *m, preference, note)
+
+	// sign-ups from the subscription channel get an ephemeral reply, dm sign-ups stay in the menu
+	if i.GuildID != "" {
+		respondEphemeral(s, i, content)
+		return
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: raidAttendBackComponents(nil),
+		},
+	})
+}
+
+// cancelRaidAttend cancels the member's sign-up and promotes the waitlist. The attend's RaidSchedule must be loaded.
+func cancelRaidAttend(s *discordgo.Session, attend model.RaidAttend) string {
+	rdb.Delete(&attend)
+	recordRaidLedger(attend.RaidSchedule, attend.MemberInfo, raidCancelOutcome(attend.RaidSchedule), true)
+	if !attend.Waitlisted {
+		promoteWaitlistedAttends(s, attend.RaidScheduleID)
+	}
+
+	return fmt.Sprintf("[%s] %s (%d 트라이) 참가 신청이 취소되었습니다.",
+		attend.RaidSchedule.Raid.RaidName, attend.RaidSchedule.StartTime.In(loc).Format("2006-01-02 15:04"), attend.RaidSchedule.TryCount)
+}
+
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// raidSubscriptionButtonHandler handles the 참가/취소 buttons on the subscription message.
+func raidSubscriptionButtonHandler(s *discordgo.Session, i *discordgo.InteractionCreate, scheduleID string, join bool) {
+	// get member info
+	memberInfo := cache.GetGuildMember(interactionUserID(i))
+	if memberInfo == nil {
+		respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+		return
+	}
+	// get schedule
+	var schedule model.RaidSchedule
+	err := rdb.Preload("Raid").First(&schedule, scheduleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondEphemeral(s, i, "레이드 일정을 찾을 수 없습니다.")
+		return
+	}
+
+	if join {
+		content, components := raidJoinPrompt(schedule, memberInfo, "")
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:    content,
+				Components: components,
+				Flags:      discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	// get attend, with any character
+	var attend model.RaidAttend
+	err = rdb.Where("mention = ? AND raid_schedule_id = ? AND canceled = ?", fmt.Sprintf("<@%s>", memberInfo.User.ID), schedule.ID, false).First(&attend).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondEphemeral(s, i, fmt.Sprintf("[%s] %s (%d 트라이) 참가 신청 내역이 없습니다.",
+			schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount))
+		return
+	}
+
+	// same as the dm flow, cancel is only allowed before the subscription end time
+	if !schedule.SubscriptionEndTime.After(time.Now()) {
+		respondEphemeral(s, i, fmt.Sprintf("[%s] %s (%d 트라이) 참가신청 기한이 마감되어 취소할 수 없습니다. 공대장에게 문의해 주세요.",
+			schedule.Raid.RaidName, schedule.StartTime.In(loc).Format("2006-01-02 15:04"), schedule.TryCount))
+		return
+	}
+
+	attend.RaidSchedule = schedule
+	respondEphemeral(s, i, cancelRaidAttend(s, attend))
+}
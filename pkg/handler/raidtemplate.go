@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/discord"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"gorm.io/gorm"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// raidTemplateWeeks is how many weeks ahead recurring schedules are materialized.
+const raidTemplateWeeks = 2
+
+var weekdayNames = []string{"일", "월", "화", "수", "목", "금", "토"}
+
+func parseClock(clock string) (int, int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, 0, err
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// templateOccurrenceInWeek returns the occurrence of the template in the week (starting on Monday, KST) containing t.
+func templateOccurrenceInWeek(tpl model.RaidScheduleTemplate, t time.Time) time.Time {
+	hour, minute, _ := parseClock(tpl.StartClock)
+	d := t.In(loc)
+	offset := (int(d.Weekday()) + 6) % 7
+	monday := time.Date(d.Year(), d.Month(), d.Day()-offset, 0, 0, 0, 0, loc)
+	return time.Date(monday.Year(), monday.Month(), monday.Day()+(tpl.Weekday+6)%7, hour, minute, 0, 0, loc)
+}
+
+// nextTemplateOccurrences returns the upcoming start times of the template within the given weeks.
+func nextTemplateOccurrences(tpl model.RaidScheduleTemplate, now time.Time, weeks int) []time.Time {
+	var occurrences []time.Time
+	for w := 0; w <= weeks; w++ {
+		t := templateOccurrenceInWeek(tpl, now.AddDate(0, 0, 7*w))
+		if t.After(now) && t.Before(now.AddDate(0, 0, 7*weeks)) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences
+}
+
+func describeRaidScheduleTemplate(tpl model.RaidScheduleTemplate) string {
+	desc := fmt.Sprintf("[%s] 매주 %s %s (%d트라이, 시작 %d시간 전 마감",
+		tpl.Raid.RaidName, weekdayNames[tpl.Weekday], tpl.StartClock, tpl.TryCount, tpl.SubscriptionCloseOffset/60)
+	if tpl.Capacity > 0 {
+		desc += fmt.Sprintf(", 정원 %d명", tpl.Capacity)
+	}
+	desc += ")"
+	if tpl.Paused {
+		desc += " - 일시정지"
+	}
+	return desc
+}
+
+func listFutureTemplateSchedules(templateID uint) []model.RaidSchedule {
+	var schedules []model.RaidSchedule
+	rdb.Preload("Raid").Where("template_id = ? AND start_time > ?", templateID, time.Now().UTC()).Find(&schedules)
+	return schedules
+}
+
+// skipTemplateOccurrence keeps the refresh from re-creating the occurrence an admin removed.
+func skipTemplateOccurrence(schedule model.RaidSchedule) {
+	if schedule.TemplateID == 0 {
+		return
+	}
+	rdb.Create(&model.RaidTemplateSkip{TemplateID: schedule.TemplateID, StartTime: schedule.StartTime})
+}
+
+func listFutureTemplateSkips(templateID uint) []model.RaidTemplateSkip {
+	var skips []model.RaidTemplateSkip
+	rdb.Where("template_id = ? AND start_time > ?", templateID, time.Now().UTC()).Find(&skips)
+	return skips
+}
+
+// removeRaidSchedule cancels the schedule, the subscription message is kept as the cancellation notice.
+func removeRaidSchedule(dg *discordgo.Session, schedule model.RaidSchedule) {
+	notifyRaidScheduleRemoval(dg, schedule)
+	rdb.Delete(&schedule)
+	rdb.Where("raid_schedule_id = ?", schedule.ID).Delete(&model.RaidAttendLedger{})
+}
+
+// RaidScheduleTemplateRefresh materializes the upcoming schedules of all active templates.
+func RaidScheduleTemplateRefresh(dg *discordgo.Session) error {
+	var templates []model.RaidScheduleTemplate
+	if err := rdb.Preload("Raid").Where("paused = ?", false).Find(&templates).Error; err != nil {
+		return fmt.Errorf("failed to get raid schedule templates: %w", err)
+	}
+
+	now := time.Now()
+	for _, tpl := range templates {
+		existing := listFutureTemplateSchedules(tpl.ID)
+		skips := listFutureTemplateSkips(tpl.ID)
+		for _, t := range nextTemplateOccurrences(tpl, now, raidTemplateWeeks) {
+			if slices.ContainsFunc(existing, func(sc model.RaidSchedule) bool { return sc.StartTime.Equal(t) }) {
+				continue
+			}
+			if slices.ContainsFunc(skips, func(sk model.RaidTemplateSkip) bool { return sk.StartTime.Equal(t) }) {
+				continue
+			}
+
+			schedule := model.RaidSchedule{
+				RaidID:              tpl.RaidID,
+				TryCount:            tpl.TryCount,
+				StartTime:           t.UTC(),
+				SubscriptionEndTime: t.Add(-time.Duration(tpl.SubscriptionCloseOffset) * time.Minute).UTC(),
+				Capacity:            tpl.Capacity,
+				TemplateID:          tpl.ID,
+			}
+			if err := createRaidSchedule(dg, &schedule); err != nil {
+				fmt.Println("failed to create raid schedule from template:", err)
+				continue
+			}
+		}
+	}
+	return nil
+}
+
+// applyRaidScheduleTemplate updates the future, not-yet-started occurrences of the template.
+func applyRaidScheduleTemplate(dg *discordgo.Session, tpl model.RaidScheduleTemplate) {
+	for _, sc := range listFutureTemplateSchedules(tpl.ID) {
+		if tpl.Paused {
+			removeRaidSchedule(dg, sc)
+			continue
+		}
+
+		t := templateOccurrenceInWeek(tpl, sc.StartTime)
+		if !t.After(time.Now()) {
+			removeRaidSchedule(dg, sc)
+			continue
+		}
+
+		before := sc
+		sc.TryCount = tpl.TryCount
+		sc.StartTime = t.UTC()
+		sc.SubscriptionEndTime = t.Add(-time.Duration(tpl.SubscriptionCloseOffset) * time.Minute).UTC()
+		sc.Capacity = tpl.Capacity
+		rdb.Save(&sc)
+		promoteWaitlistedAttends(dg, sc.ID)
+		resetRaidReminders(before, sc)
+		notifyRaidScheduleChange(dg, before, sc)
+	}
+
+	if err := RaidScheduleTemplateRefresh(dg); err != nil {
+		fmt.Println("failed to refresh raid schedule templates:", err)
+	}
+}
+
+func raidTemplateListHandler(s *discordgo.Session, i *discordgo.InteractionCreate, notice string) {
+	var templates []model.RaidScheduleTemplate
+	rdb.Preload("Raid").Find(&templates)
+
+	msg := ""
+	if notice != "" {
+		msg += notice + "\n\n"
+	}
+	msg += "**[반복 일정 목록]**\n"
+	var selectOptions []discordgo.SelectMenuOption
+	for _, tpl := range templates {
+		msg += fmt.Sprintf("* %s\n", describeRaidScheduleTemplate(tpl))
+		if len(selectOptions) < 25 {
+			selectOptions = append(selectOptions, discordgo.SelectMenuOption{
+				Label: describeRaidScheduleTemplate(tpl),
+				Value: fmt.Sprintf("%d", tpl.ID),
+			})
+		}
+	}
+	if len(templates) == 0 {
+		msg += "* 없음\n"
+	}
+
+	var components []discordgo.MessageComponent
+	if len(selectOptions) > 0 {
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    "admin-template-select",
+					Placeholder: "반복 일정 선택",
+					Options:     selectOptions,
+				},
+			},
+		})
+	}
+	components = append(components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "반복 일정 추가",
+				Style:    discordgo.PrimaryButton,
+				CustomID: "admin-template-add",
+			},
+			discordgo.Button{
+				Label:    "처음으로 돌아가기",
+				Style:    discordgo.SecondaryButton,
+				CustomID: "admin-landing-page",
+			},
+		},
+	})
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: components,
+		},
+	})
+}
+
+func raidTemplateDetailHandler(s *discordgo.Session, i *discordgo.InteractionCreate, templateID string) {
+	// get template
+	var tpl model.RaidScheduleTemplate
+	err := rdb.Preload("Raid").First(&tpl, templateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	msg := fmt.Sprintf("**%s**\n\n", describeRaidScheduleTemplate(tpl))
+	msg += "**[예정된 일정]**\n"
+	schedules := listFutureTemplateSchedules(tpl.ID)
+	for _, sc := range schedules {
+		msg += fmt.Sprintf("* %s (%d트라이)\n", sc.StartTime.In(loc).Format("2006-01-02 15:04"), sc.TryCount)
+	}
+	if len(schedules) == 0 {
+		msg += "* 없음\n"
+	}
+	msg += "\n반복 일정을 수정하거나 일시정지하면 아직 시작하지 않은 일정에만 반영됩니다."
+
+	toggleLabel := "일시정지"
+	if tpl.Paused {
+		toggleLabel = "재개"
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "수정",
+							Style:    discordgo.PrimaryButton,
+							CustomID: fmt.Sprintf("admin-template-edit_%d", tpl.ID),
+						},
+						discordgo.Button{
+							Label:    toggleLabel,
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("admin-template-toggle_%d", tpl.ID),
+						},
+						discordgo.Button{
+							Label:    "삭제",
+							Style:    discordgo.DangerButton,
+							CustomID: fmt.Sprintf("admin-template-remove_%d", tpl.ID),
+						},
+						discordgo.Button{
+							Label:    "반복 일정으로 돌아가기",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "admin-template",
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func raidTemplateToggleHandler(s *discordgo.Session, i *discordgo.InteractionCreate, templateID string) {
+	// get template
+	var tpl model.RaidScheduleTemplate
+	err := rdb.Preload("Raid").First(&tpl, templateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	tpl.Paused = !tpl.Paused
+	rdb.Save(&tpl)
+	applyRaidScheduleTemplate(s, tpl)
+
+	raidTemplateDetailHandler(s, i, templateID)
+}
+
+func raidTemplateRemoveHandler(s *discordgo.Session, i *discordgo.InteractionCreate, templateID string) {
+	// get template
+	var tpl model.RaidScheduleTemplate
+	err := rdb.Preload("Raid").First(&tpl, templateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	// remove future occurrences, then the template
+	for _, sc := range listFutureTemplateSchedules(tpl.ID) {
+		removeRaidSchedule(s, sc)
+	}
+	rdb.Delete(&tpl)
+
+	raidTemplateListHandler(s, i, fmt.Sprintf("반복 일정 '%s'가 삭제되었습니다.", describeRaidScheduleTemplate(tpl)))
+}
+
+func raidTemplateEditModal(s *discordgo.Session, i *discordgo.InteractionCreate, templateID string) {
+	// get template
+	var tpl model.RaidScheduleTemplate
+	err := rdb.First(&tpl, templateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	discord.SendRaidScheduleTemplateModal(s, i.Interaction, "반복 일정 수정", fmt.Sprintf("edit-raid-template-modal_%d", tpl.ID),
+		weekdayNames[tpl.Weekday], tpl.StartClock, tpl.TryCount, tpl.SubscriptionCloseOffset/60, tpl.Capacity)
+}
+
+func raidTemplateModalHandler(s *discordgo.Session, i *discordgo.InteractionCreate, tpl model.RaidScheduleTemplate) {
+	modalData := i.ModalSubmitData().Components
+
+	var weekday, startClock, tryCount, closeOffset, capacity string
+	for _, comp := range modalData {
+		if ar, ok := comp.(*discordgo.ActionsRow); ok {
+			if ti, ok := ar.Components[0].(*discordgo.TextInput); ok {
+				switch ti.CustomID {
+				case "weekday":
+					weekday = strings.TrimSpace(ti.Value)
+				case "start-clock":
+					startClock = strings.TrimSpace(ti.Value)
+				case "try-count":
+					tryCount = strings.TrimSpace(ti.Value)
+				case "subscription-close-offset":
+					closeOffset = strings.TrimSpace(ti.Value)
+				case "capacity":
+					capacity = strings.TrimSpace(ti.Value)
+				}
+			}
+		}
+	}
+
+	wd := slices.Index(weekdayNames, strings.TrimSuffix(weekday, "요일"))
+	if wd < 0 {
+		raidTemplateListHandler(s, i, fmt.Sprintf("요일 '%s'가 올바르지 않습니다. (%s)", weekday, strings.Join(weekdayNames, "/")))
+		return
+	}
+	hour, minute, err := parseClock(startClock)
+	if err != nil {
+		raidTemplateListHandler(s, i, fmt.Sprintf("시작 시간 '%s'가 올바르지 않습니다. (예: 21:00)", startClock))
+		return
+	}
+	tryCountInt, err := strconv.Atoi(tryCount)
+	if err != nil || tryCountInt < 1 {
+		raidTemplateListHandler(s, i, "트라이 횟수가 올바르지 않습니다.")
+		return
+	}
+	closeOffsetInt, err := strconv.Atoi(closeOffset)
+	if err != nil || closeOffsetInt < 0 {
+		raidTemplateListHandler(s, i, "모집 마감 시간이 올바르지 않습니다.")
+		return
+	}
+	capacityInt, err := strconv.Atoi(capacity)
+	if err != nil || capacityInt < 0 {
+		raidTemplateListHandler(s, i, "정원이 올바르지 않습니다.")
+		return
+	}
+
+	tpl.Weekday = wd
+	tpl.StartClock = fmt.Sprintf("%02d:%02d", hour, minute)
+	tpl.TryCount = tryCountInt
+	tpl.SubscriptionCloseOffset = closeOffsetInt * 60
+	tpl.Capacity = capacityInt
+
+	notice := "반복 일정이 수정되었습니다."
+	if tpl.ID == 0 {
+		notice = "반복 일정이 추가되었습니다."
+	}
+	rdb.Save(&tpl)
+	rdb.Preload("Raid").First(&tpl, tpl.ID)
+	applyRaidScheduleTemplate(s, tpl)
+
+	raidTemplateListHandler(s, i, notice)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sokdak/eternity-bot/pkg/model"
+)
+
+func kstTime(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2026, month, day, hour, minute, 0, 0, loc)
+}
+
+func assertOccurrences(t *testing.T, tpl model.RaidScheduleTemplate, now time.Time, weeks int, want ...time.Time) {
+	t.Helper()
+	got := nextTemplateOccurrences(tpl, now, weeks)
+	if !slices.EqualFunc(got, want, time.Time.Equal) {
+		t.Errorf("nextTemplateOccurrences(%s %s, %s, %d) = %v, want %v",
+			time.Weekday(tpl.Weekday), tpl.StartClock, now.Format(time.DateTime), weeks, got, want)
+	}
+}
+
+func TestNextTemplateOccurrences(t *testing.T) {
+	wednesday := model.RaidScheduleTemplate{Weekday: 3, StartClock: "21:00"}
+
+	// monday 2026-10-19 creates this week's and next week's wednesdays
+	assertOccurrences(t, wednesday, kstTime(time.October, 19, 10, 0), 2,
+		kstTime(time.October, 21, 21, 0), kstTime(time.October, 28, 21, 0))
+	assertOccurrences(t, wednesday, kstTime(time.October, 19, 10, 0), 1,
+		kstTime(time.October, 21, 21, 0))
+
+	// once this week's run has passed, the window starts from next week
+	assertOccurrences(t, wednesday, kstTime(time.October, 22, 10, 0), 2,
+		kstTime(time.October, 28, 21, 0), kstTime(time.November, 4, 21, 0))
+	assertOccurrences(t, wednesday, kstTime(time.October, 21, 21, 0), 2,
+		kstTime(time.October, 28, 21, 0))
+}
+
+func TestNextTemplateOccurrencesSundayEndsTheWeek(t *testing.T) {
+	sunday := model.RaidScheduleTemplate{Weekday: 0, StartClock: "20:30"}
+	assertOccurrences(t, sunday, kstTime(time.October, 19, 10, 0), 2,
+		kstTime(time.October, 25, 20, 30), kstTime(time.November, 1, 20, 30))
+}
+
+func TestNextTemplateOccurrencesUsesSeoulClock(t *testing.T) {
+	// 20:00 KST is 11:00 UTC; the run at 21:00 KST the same day is still ahead
+	wednesday := model.RaidScheduleTemplate{Weekday: 3, StartClock: "21:00"}
+	assertOccurrences(t, wednesday, kstTime(time.October, 21, 20, 0).UTC(), 1,
+		kstTime(time.October, 21, 21, 0))
+}
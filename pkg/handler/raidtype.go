@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+type raidTypeInfo struct {
+	MinLevel int
+	// RequiredJobs is the recommended composition keyed by sub role (비숍) or main role (전사).
+	RequiredJobs    map[string]int
+	DefaultTryCount int
+	// ExpectedDuration is how long a single try usually takes, used for overlap detection.
+	ExpectedDuration time.Duration
+}
+
+// defaultRaidDuration is used for raids without a known raid type.
+const defaultRaidDuration = time.Hour
+
+var raidTypeOrder = []string{"자쿰", "혼테일", "파풀라투스", "피아누스"}
+
+// otherRaidType groups the raids whose type is not one of raidTypeOrder in the reports.
+const otherRaidType = "기타"
+
+var raidTypeList = map[string]raidTypeInfo{
+	"자쿰": {
+		MinLevel:         100,
+		RequiredJobs:     map[string]int{"비숍": 2, "전사": 4},
+		DefaultTryCount:  3,
+		ExpectedDuration: time.Hour,
+	},
+	"혼테일": {
+		MinLevel:         120,
+		RequiredJobs:     map[string]int{"비숍": 3, "전사": 4, "궁수": 2},
+		DefaultTryCount:  1,
+		ExpectedDuration: 2 * time.Hour,
+	},
+	"파풀라투스": {
+		MinLevel:         110,
+		RequiredJobs:     map[string]int{"비숍": 1, "전사": 1},
+		DefaultTryCount:  2,
+		ExpectedDuration: time.Hour,
+	},
+	"피아누스": {
+		MinLevel:         100,
+		RequiredJobs:     map[string]int{"비숍": 1},
+		DefaultTryCount:  2,
+		ExpectedDuration: 30 * time.Minute,
+	},
+}
+
+func getRaidType(raid model.Raid) (raidTypeInfo, bool) {
+	rt, ok := raidTypeList[strings.TrimSpace(raid.Type)]
+	return rt, ok
+}
+
+// raidReportType returns the raid type the raid is reported under, otherRaidType for unknown types.
+func raidReportType(raid model.Raid) string {
+	if _, ok := getRaidType(raid); !ok {
+		return otherRaidType
+	}
+	return strings.TrimSpace(raid.Type)
+}
+
+// guessRaidType finds the known raid type that the free-text type or the raid name mentions.
+func guessRaidType(raid model.Raid) (string, bool) {
+	for _, rt := range raidTypeOrder {
+		if strings.Contains(raid.Type, rt) || strings.Contains(raid.RaidName, rt) {
+			return rt, true
+		}
+	}
+	return "", false
+}
+
+// migrateLegacyRaidTypes moves raids created before the type had to be one of raidTypeList onto a known type
+// when the old text names one, so they get the level checks and the report again.
+func migrateLegacyRaidTypes() {
+	var raids []model.Raid
+	rdb.Find(&raids)
+	for _, raid := range raids {
+		if _, ok := getRaidType(raid); ok {
+			continue
+		}
+		rt, ok := guessRaidType(raid)
+		if !ok {
+			fmt.Printf("raid %s has unknown type '%s', reported as %s\n", raid.RaidName, raid.Type, otherRaidType)
+			continue
+		}
+		rdb.Model(&model.Raid{}).Where("id = ?", raid.ID).Update("type", rt)
+	}
+}
+
+func raidDuration(raid model.Raid) time.Duration {
+	if rt, ok := getRaidType(raid); ok && rt.ExpectedDuration > 0 {
+		return rt.ExpectedDuration
+	}
+	return defaultRaidDuration
+}
+
+// missingRequiredJobs returns the required jobs that are not yet filled by the attendees, e.g. "비숍 1명".
+func missingRequiredJobs(rt raidTypeInfo, attends []model.RaidAttend) []string {
+	var jobs []string
+	for job := range rt.RequiredJobs {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+
+	var missing []string
+	for _, job := range jobs {
+		if n := rt.RequiredJobs[job] - countAttendsByJob(attends, job); n > 0 {
+			missing = append(missing, fmt.Sprintf("%s %d명", job, n))
+		}
+	}
+	return missing
+}
@@ -0,0 +1,383 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sokdak/eternity-bot/pkg/cache"
+	"github.com/sokdak/eternity-bot/pkg/environment"
+	"github.com/sokdak/eternity-bot/pkg/model"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// voiceRankMaxLines is how many members the voice leaderboard shows.
+	voiceRankMaxLines = 20
+	// voiceStatsDays is the period the member voice stats cover.
+	voiceStatsDays = 30
+	// voiceTopLines is how many channels and companions the member voice stats show.
+	voiceTopLines = 5
+)
+
+var voiceSessionLock = &sync.Mutex{}
+
+// trackVoiceSession closes the member's open session and opens a new one when joining or moving to a channel.
+func trackVoiceSession(userID, channelID string, now time.Time) {
+	voiceSessionLock.Lock()
+	defer voiceSessionLock.Unlock()
+
+	var open model.VoiceSession
+	if adb.Where("discord_user_id = ? AND open = ?", userID, true).Limit(1).Find(&open).RowsAffected > 0 {
+		if open.ChannelID == channelID {
+			// mute, deafen and such keep the session
+			return
+		}
+		countVoiceSeconds(open, now)
+		open.Open = false
+		open.LeftAt = now
+		open.LastSeenAt = now
+		adb.Save(&open)
+	}
+
+	if channelID == "" {
+		return
+	}
+	adb.Create(&model.VoiceSession{
+		DiscordUserID: userID,
+		ChannelID:     channelID,
+		JoinedAt:      now,
+		LastSeenAt:    now,
+		Open:          true,
+	})
+}
+
+// VoiceSessionHeartbeat counts the voice time of the open sessions so far and marks them as seen now.
+func VoiceSessionHeartbeat() error {
+	voiceSessionLock.Lock()
+	defer voiceSessionLock.Unlock()
+
+	now := time.Now().UTC()
+	var open []model.VoiceSession
+	adb.Where("open = ?", true).Find(&open)
+
+	err := adb.Model(&model.VoiceSession{}).Where("open = ?", true).Update("last_seen_at", now).Error
+	if err != nil {
+		return fmt.Errorf("failed to update voice sessions: %w", err)
+	}
+	for _, vs := range open {
+		countVoiceSeconds(vs, now)
+	}
+	return nil
+}
+
+// reconcileVoiceSessions ends the sessions left open by a restart where they were last seen,
+// and opens sessions for the members currently in voice channels.
+func reconcileVoiceSessions(s *discordgo.Session, voiceStates []*discordgo.VoiceState) {
+	voiceSessionLock.Lock()
+	var stale []model.VoiceSession
+	adb.Where("open = ?", true).Find(&stale)
+	for _, vs := range stale {
+		vs.Open = false
+		vs.LeftAt = vs.LastSeenAt
+		adb.Save(&vs)
+	}
+	voiceSessionLock.Unlock()
+
+	now := time.Now().UTC()
+	for _, vs := range voiceStates {
+		if vs.ChannelID == "" || vs.UserID == s.State.User.ID {
+			continue
+		}
+		if vs.Member != nil && vs.Member.User != nil && vs.Member.User.Bot {
+			continue
+		}
+		trackVoiceSession(vs.UserID, vs.ChannelID, now)
+	}
+}
+
+func onVoiceGuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if g.ID != environment.DiscordGuildID {
+		return
+	}
+	reconcileVoiceSessions(s, g.VoiceStates)
+}
+
+// listVoiceSessions returns the sessions overlapping [since, until).
+func listVoiceSessions(since, until time.Time) []model.VoiceSession {
+	var sessions []model.VoiceSession
+	adb.Where("joined_at < ? AND (open = ? OR left_at > ?)", until.UTC(), true, since.UTC()).Find(&sessions)
+	return sessions
+}
+
+// voiceSessionSpan returns the part of the session within [since, until), open sessions last until now.
+func voiceSessionSpan(vs model.VoiceSession, since, until time.Time) (time.Time, time.Time) {
+	start, end := vs.JoinedAt, vs.LeftAt
+	if vs.Open {
+		end = time.Now()
+	}
+	if start.Before(since) {
+		start = since
+	}
+	if end.After(until) {
+		end = until
+	}
+	return start, end
+}
+
+func voiceSessionSeconds(vs model.VoiceSession, since, until time.Time) int {
+	start, end := voiceSessionSpan(vs, since, until)
+	if !end.After(start) {
+		return 0
+	}
+	return int(end.Sub(start).Seconds())
+}
+
+func formatVoiceDuration(seconds int) string {
+	minutes := seconds / 60
+	if minutes < 60 {
+		return fmt.Sprintf("%d분", minutes)
+	}
+	return fmt.Sprintf("%d시간 %d분", minutes/60, minutes%60)
+}
+
+// memberDisplayName returns the main character nickname of the member, or the username when it cannot be parsed.
+func memberDisplayName(userID string) string {
+	member := cache.GetGuildMember(userID)
+	if member == nil {
+		return fmt.Sprintf("<@%s>", userID)
+	}
+	if m, err := GetMainMemberInfo(member); err == nil {
+		return m.Nickname
+	}
+	return member.User.Username
+}
+
+// sortedVoiceTotals returns the keys of the totals, most seconds first.
+func sortedVoiceTotals(totals map[string]int) []string {
+	var keys []string
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if totals[keys[i]] == totals[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return totals[keys[i]] > totals[keys[j]]
+	})
+	return keys
+}
+
+// voiceWeekStart returns Monday 00:00 of the week weeksAgo weeks before the current one.
+func voiceWeekStart(weeksAgo int) time.Time {
+	now := time.Now().In(loc)
+	daysSinceMonday := (int(now.Weekday()) + 6) % 7
+	monday := time.Date(now.Year(), now.Month(), now.Day()-daysSinceMonday, 0, 0, 0, 0, loc)
+	return monday.AddDate(0, 0, -7*weeksAgo)
+}
+
+func renderVoiceRank(weeksAgo int) string {
+	since := voiceWeekStart(weeksAgo)
+	until := since.AddDate(0, 0, 7)
+
+	totals := make(map[string]int)
+	for _, vs := range listVoiceSessions(since, until) {
+		if n := voiceSessionSeconds(vs, since, until); n > 0 {
+			totals[vs.DiscordUserID] += n
+		}
+	}
+
+	label := "이번 주"
+	if weeksAgo > 0 {
+		label = "지난 주"
+	}
+	msg := fmt.Sprintf("**[%s 음성 채널 순위]** %s ~ %s\n", label, since.Format("01월 02일"), until.AddDate(0, 0, -1).Format("01월 02일"))
+	if len(totals) == 0 {
+		return msg + "기록된 음성 채널 활동이 없습니다.\n"
+	}
+	optOuts := listLeaderboardOptOuts()
+	rank := 0
+	for _, userID := range sortedVoiceTotals(totals) {
+		if optOuts[userID] {
+			continue
+		}
+		if rank >= voiceRankMaxLines {
+			break
+		}
+		rank++
+		msg += fmt.Sprintf("%d. %s - %s\n", rank, memberDisplayName(userID), formatVoiceDuration(totals[userID]))
+	}
+	return msg
+}
+
+// renderVoiceStats renders the member's voice time, channels and the members most often in the same channel.
+func renderVoiceStats(member *discordgo.Member) string {
+	userID := member.User.ID
+	now := time.Now()
+	weekStart := voiceWeekStart(0)
+	since := now.AddDate(0, 0, -voiceStatsDays)
+	sessions := listVoiceSessions(since, now)
+
+	var week, total int
+	channels := make(map[string]int)
+	var own []model.VoiceSession
+	for _, vs := range sessions {
+		if vs.DiscordUserID != userID {
+			continue
+		}
+		own = append(own, vs)
+		week += voiceSessionSeconds(vs, weekStart, now)
+		n := voiceSessionSeconds(vs, since, now)
+		total += n
+		channels[vs.ChannelID] += n
+	}
+
+	msg := fmt.Sprintf("**[%s 님의 음성 채널 기록]**\n", memberDisplayName(userID))
+	msg += fmt.Sprintf("* 이번 주: %s\n", formatVoiceDuration(week))
+	msg += fmt.Sprintf("* 최근 %d일: %s (%d회 접속)\n", voiceStatsDays, formatVoiceDuration(total), len(own))
+	if len(own) == 0 {
+		return msg
+	}
+
+	msg += "\n**자주 머문 채널**\n"
+	for idx, ch := range sortedVoiceTotals(channels) {
+		if idx >= voiceTopLines {
+			break
+		}
+		msg += fmt.Sprintf("* <#%s>: %s\n", ch, formatVoiceDuration(channels[ch]))
+	}
+
+	// time spent in the same channel with the other members, leaving out the ones who opted out
+	optOuts := listLeaderboardOptOuts()
+	together := make(map[string]int)
+	for _, mine := range own {
+		myStart, myEnd := voiceSessionSpan(mine, since, now)
+		for _, other := range sessions {
+			if other.DiscordUserID == userID || other.ChannelID != mine.ChannelID || optOuts[other.DiscordUserID] {
+				continue
+			}
+			if n := voiceSessionSeconds(other, myStart, myEnd); n > 0 {
+				together[other.DiscordUserID] += n
+			}
+		}
+	}
+	if len(together) > 0 {
+		msg += "\n**함께한 길드원**\n"
+		for idx, other := range sortedVoiceTotals(together) {
+			if idx >= voiceTopLines {
+				break
+			}
+			msg += fmt.Sprintf("* %s: %s\n", memberDisplayName(other), formatVoiceDuration(together[other]))
+		}
+	}
+	return msg
+}
+
+func voiceComponents(userID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "음성 기록",
+					Style:    discordgo.PrimaryButton,
+					CustomID: "voice-member_" + userID,
+				},
+				discordgo.Button{
+					Label:    "이번 주 순위",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "voice-rank_0_" + userID,
+				},
+				discordgo.Button{
+					Label:    "지난 주 순위",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "voice-rank_1_" + userID,
+				},
+			},
+		},
+	}
+}
+
+func voiceHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		data, ok := i.Data.(discordgo.ApplicationCommandInteractionData)
+		if !ok || data.Name != "음성" {
+			return
+		}
+
+		userID := interactionUserID(i)
+		for _, opt := range data.Options {
+			if opt.Name == "길드원" {
+				userID = opt.UserValue(nil).ID
+			}
+		}
+
+		member := cache.GetGuildMember(userID)
+		if member == nil {
+			respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+			return
+		}
+		if activityHiddenFrom(userID, interactionUserID(i)) {
+			respondEphemeral(s, i, "활동 기록을 공개하지 않은 길드원입니다.")
+			return
+		}
+
+		var flags discordgo.MessageFlags
+		if i.GuildID != "" {
+			flags = discordgo.MessageFlagsEphemeral
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:         renderVoiceStats(member),
+				Components:      voiceComponents(userID),
+				Flags:           flags,
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+	case discordgo.InteractionMessageComponent:
+		args := strings.Split(i.MessageComponentData().CustomID, "_")
+		if len(args) < 2 {
+			return
+		}
+
+		var msg, userID string
+		switch args[0] {
+		case "voice-member":
+			userID = args[1]
+			member := cache.GetGuildMember(userID)
+			if member == nil {
+				respondEphemeral(s, i, "영원길드 멤버가 아닙니다.")
+				return
+			}
+			if activityHiddenFrom(userID, interactionUserID(i)) {
+				respondEphemeral(s, i, "활동 기록을 공개하지 않은 길드원입니다.")
+				return
+			}
+			msg = renderVoiceStats(member)
+		case "voice-rank":
+			if len(args) < 3 {
+				return
+			}
+			weeksAgo, err := strconv.Atoi(args[1])
+			if err != nil {
+				return
+			}
+			userID = args[2]
+			msg = renderVoiceRank(weeksAgo)
+		default:
+			return
+		}
+
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:         msg,
+				Components:      voiceComponents(userID),
+				AllowedMentions: &discordgo.MessageAllowedMentions{},
+			},
+		})
+	}
+}
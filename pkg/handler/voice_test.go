@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sokdak/eternity-bot/pkg/model"
+)
+
+func TestVoiceSessionSeconds(t *testing.T) {
+	base := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	closed := model.VoiceSession{JoinedAt: base, LeftAt: base.Add(time.Hour)}
+
+	check := func(since, until time.Time, want int) {
+		t.Helper()
+		if got := voiceSessionSeconds(closed, since, until); got != want {
+			t.Errorf("[%s, %s): got %d, want %d", since.Format(time.TimeOnly), until.Format(time.TimeOnly), got, want)
+		}
+	}
+	check(base.Add(-time.Hour), base.Add(2*time.Hour), 3600)
+	check(base.Add(15*time.Minute), base.Add(2*time.Hour), 2700)
+	check(base, base.Add(30*time.Minute), 1800)
+	check(base.Add(2*time.Hour), base.Add(3*time.Hour), 0)
+	check(base.Add(-2*time.Hour), base.Add(-time.Hour), 0)
+
+	// an open session lasts until now
+	open := model.VoiceSession{JoinedAt: time.Now().Add(-2 * time.Hour), Open: true}
+	if got := voiceSessionSeconds(open, open.JoinedAt, open.JoinedAt.Add(30*time.Minute)); got != 1800 {
+		t.Errorf("open session clipped at until: got %d, want 1800", got)
+	}
+	if got := voiceSessionSeconds(open, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)); got != 0 {
+		t.Errorf("open session in the future: got %d, want 0", got)
+	}
+}
+
+func TestTrackVoiceSession(t *testing.T) {
+	db := useActivityDB(t, true)
+	if err := db.AutoMigrate(&model.VoiceSession{}); err != nil {
+		t.Fatal(err)
+	}
+
+	joined := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	trackVoiceSession("alice", "lobby", joined)
+	// muting reports the same channel again
+	trackVoiceSession("alice", "lobby", joined.Add(10*time.Minute))
+	trackVoiceSession("alice", "raid", joined.Add(20*time.Minute))
+	trackVoiceSession("alice", "", joined.Add(50*time.Minute))
+
+	var sessions []model.VoiceSession
+	db.Order("id").Find(&sessions)
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	for idx, want := range []struct {
+		channel string
+		minutes int
+	}{{"lobby", 20}, {"raid", 30}} {
+		vs := sessions[idx]
+		if vs.ChannelID != want.channel || vs.Open || vs.LeftAt.Sub(vs.JoinedAt) != time.Duration(want.minutes)*time.Minute {
+			t.Errorf("session %d: got %s from %s to %s (open %v), want %d minutes in %s",
+				idx, vs.ChannelID, vs.JoinedAt, vs.LeftAt, vs.Open, want.minutes, want.channel)
+		}
+	}
+
+	// closing a session counts its seconds into the voice counters
+	voice := make(map[string]int)
+	for k, n := range pendingActivityCounters {
+		if k.Kind == model.ActivityKindVoice {
+			voice[k.ChannelID] += n
+		}
+	}
+	if voice["lobby"] != 20*60 || voice["raid"] != 30*60 {
+		t.Errorf("got voice seconds %v, want 1200 in lobby and 1800 in raid", voice)
+	}
+}
+
+func TestSortedVoiceTotals(t *testing.T) {
+	got := sortedVoiceTotals(map[string]int{"b": 60, "a": 60, "c": 3600, "d": 1})
+	if want := []string{"c", "a", "b", "d"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := formatVoiceDuration(3599); got != "59분" {
+		t.Errorf("formatVoiceDuration(3599) = %q", got)
+	}
+	if got := formatVoiceDuration(5400); got != "1시간 30분" {
+		t.Errorf("formatVoiceDuration(5400) = %q", got)
+	}
+}
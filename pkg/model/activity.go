@@ -0,0 +1,70 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// InactivityCheckIn is a check-in DM sent to an inactive member.
+// It is resolved when the member responds or becomes active, or when an officer keeps the member.
+type InactivityCheckIn struct {
+	gorm.Model
+	DiscordUserID string `gorm:"index"`
+	Nickname      string
+	SentAt        time.Time
+	RespondedAt   time.Time
+	ProposedAt    time.Time
+	Resolved      bool
+}
+
+// activity counter kinds
+const (
+	ActivityKindMessage  = "message"
+	ActivityKindEdit     = "edit"
+	ActivityKindReaction = "reaction"
+	ActivityKindTyping   = "typing"
+	// ActivityKindVoice counts seconds spent in voice channels.
+	ActivityKindVoice = "voice"
+)
+
+// ActivityCounter counts one kind of activity of a member in a channel on a day in Asia/Seoul, formatted as 2006-01-02.
+type ActivityCounter struct {
+	gorm.Model
+	Date          string `gorm:"uniqueIndex:idx_activity_counter"`
+	DiscordUserID string `gorm:"uniqueIndex:idx_activity_counter"`
+	ChannelID     string `gorm:"uniqueIndex:idx_activity_counter"`
+	Kind          string `gorm:"uniqueIndex:idx_activity_counter"`
+	Count         int
+}
+
+// VoiceSession is a stay of a member in a voice channel, from joining to leaving or moving.
+// LastSeenAt is refreshed while the session is open, so a session left open by a restart ends there.
+type VoiceSession struct {
+	gorm.Model
+	DiscordUserID string `gorm:"index"`
+	ChannelID     string
+	JoinedAt      time.Time
+	LastSeenAt    time.Time
+	LeftAt        time.Time
+	Open          bool `gorm:"index"`
+}
+
+// ActivityHourCounter counts the activity events of the guild in an hour of a day, both in Asia/Seoul.
+type ActivityHourCounter struct {
+	gorm.Model
+	Date  string `gorm:"uniqueIndex:idx_activity_hour_counter"`
+	Hour  int    `gorm:"uniqueIndex:idx_activity_hour_counter"`
+	Count int
+}
+
+// ActivityLeaderboardOptOut is a member who asked not to appear on the public activity leaderboards.
+type ActivityLeaderboardOptOut struct {
+	gorm.Model
+	DiscordUserID string `gorm:"uniqueIndex"`
+}
+
+// ActivityDigestPost marks the week whose activity digest has been posted, keyed by its Monday formatted as 2006-01-02.
+type ActivityDigestPost struct {
+	gorm.Model
+	Week string
+}
@@ -0,0 +1,12 @@
+package model
+
+import "gorm.io/gorm"
+
+// InfoBoardMessage is a bot-owned message of the boards in a guild info channel, in display order by Position.
+// The boards of a channel share the messages, so a board growing shifts the boards after it instead of interleaving them.
+type InfoBoardMessage struct {
+	gorm.Model
+	ChannelID string `gorm:"index"`
+	Position  int
+	MessageID string
+}
@@ -0,0 +1,19 @@
+package model
+
+import "gorm.io/gorm"
+
+// Character is an in-game character registered by a Discord user.
+// A user can register several characters and mark one of them as main.
+// Only approved characters are used in place of the Discord nickname, a character is approved by an officer
+// or when it matches the Discord nickname of the user.
+type Character struct {
+	gorm.Model
+	DiscordUserID     string `gorm:"index"`
+	Nickname          string `gorm:"uniqueIndex"`
+	MainRoleName      string
+	SubRoleName       string
+	Level             int
+	Main              bool
+	Approved          bool
+	ApprovalMessageID string
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// LevelSnapshot is a level observed for a member's character.
+// A new snapshot is only recorded when the level differs from the last one.
+type LevelSnapshot struct {
+	gorm.Model
+	DiscordUserID string `gorm:"index"`
+	Nickname      string
+	SubRoleName   string
+	Level         int
+	RecordedAt    time.Time
+}
+
+// LevelAnnouncement is the highest level announced for a member's character.
+type LevelAnnouncement struct {
+	gorm.Model
+	DiscordUserID string `gorm:"index"`
+	Nickname      string
+	Level         int
+}
+
+// LevelReportPost marks the month whose level leaderboard has been posted, formatted as 2006-01.
+type LevelReportPost struct {
+	gorm.Model
+	Month string
+}
+
+// GuildSnapshot is the daily composition of the guild, keyed by the date in Asia/Seoul formatted as 2006-01-02.
+type GuildSnapshot struct {
+	gorm.Model
+	Date         string `gorm:"uniqueIndex"`
+	MemberCount  int
+	AverageLevel float64
+	MedianLevel  int
+	Jobs         []GuildJobSnapshot       `gorm:"foreignKey:GuildSnapshotID"`
+	LevelBands   []GuildLevelBandSnapshot `gorm:"foreignKey:GuildSnapshotID"`
+}
+
+type GuildJobSnapshot struct {
+	gorm.Model
+	GuildSnapshotID uint
+	SubRoleName     string
+	Count           int
+	AverageLevel    float64
+}
+
+// GuildLevelBandSnapshot counts members by level band of 10, e.g. Band 120 is 120 ~ 129.
+type GuildLevelBandSnapshot struct {
+	gorm.Model
+	GuildSnapshotID uint
+	Band            int
+	Count           int
+}
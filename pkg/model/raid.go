@@ -13,6 +13,13 @@ type Raid struct {
 	Manager     string
 }
 
+type RaidCoManager struct {
+	gorm.Model
+	RaidID uint
+	Raid   Raid `gorm:"foreignKey:RaidID"`
+	MemberInfo
+}
+
 type RaidSchedule struct {
 	gorm.Model
 	RaidID              uint
@@ -22,12 +29,57 @@ type RaidSchedule struct {
 	StartTime           time.Time
 	MessageID           string
 	RoleID              string
+	Capacity            int
+	JobCapacity         string
+	TemplateID          uint
+}
+
+type RaidScheduleTemplate struct {
+	gorm.Model
+	RaidID                  uint
+	Raid                    Raid `gorm:"foreignKey:RaidID"`
+	Weekday                 int
+	StartClock              string
+	TryCount                int
+	SubscriptionCloseOffset int
+	Capacity                int
+	Paused                  bool
+}
+
+// RaidCalendarToken is the secret a member's calendar feed is served under, rotated on request.
+type RaidCalendarToken struct {
+	gorm.Model
+	DiscordUserID string `gorm:"uniqueIndex"`
+	Token         string `gorm:"uniqueIndex"`
+}
+
+// RaidTemplateSkip is an occurrence of a template removed by an admin, which must not be materialized again.
+type RaidTemplateSkip struct {
+	gorm.Model
+	TemplateID uint `gorm:"index"`
+	StartTime  time.Time
 }
 
+type RaidReminder struct {
+	gorm.Model
+	RaidScheduleID uint
+	RaidSchedule   RaidSchedule `gorm:"foreignKey:RaidScheduleID"`
+	Kind           string
+}
+
+const (
+	RaidAttendPreferenceMain     = "주력"
+	RaidAttendPreferenceFlexible = "유동"
+	RaidAttendPreferenceBackup   = "예비"
+)
+
 type RaidAttend struct {
 	gorm.Model
 	MemberInfo
 	Canceled       bool
+	Waitlisted     bool
+	Preference     string
+	Note           string
 	RaidScheduleID uint
 	RaidSchedule   RaidSchedule `gorm:"foreignKey:RaidScheduleID"`
 }
@@ -42,7 +94,10 @@ type RaidInfo struct {
 	RaidSchedule       RaidSchedule `gorm:"foreignKey:RaidScheduleID"`
 	DistributionRuleID uint
 	DistributionRule   DistributionRule `gorm:"foreignKey:DistributionRuleID"`
+	LootAmount         int64
 	RaidPartyInfo      []RaidPartyInfo  `gorm:"foreignKey:RaidInfoID"`
+	Payouts            []RaidPayout     `gorm:"foreignKey:RaidInfoID"`
+	Attendances        []RaidAttendance `gorm:"foreignKey:RaidInfoID"`
 }
 
 type DistributionRule struct {
@@ -51,9 +106,10 @@ type DistributionRule struct {
 	Reason            string
 	TargetPartyRole   string
 	TargetLevel       int
-	TargetAttackPower int
+	TargetAttackPower int // not applied to payouts, members' attack power is not tracked
 	DistributionType  string
-	Amount            float32
+	Amount            float32 // weight of the matching members for 역할비율
+	FixedAmount       int64   // mesos paid to each matching member for 고정
 }
 
 type RaidPartyInfo struct {
@@ -74,3 +130,53 @@ type RaidPartyMemberInfo struct {
 	MemberInfo
 	Role string
 }
+
+type RaidPayout struct {
+	gorm.Model
+	RaidInfoID uint
+	RaidInfo   RaidInfo `gorm:"foreignKey:RaidInfoID"`
+
+	MemberInfo
+	PartyRole string
+	Amount    int64
+}
+
+const (
+	RaidAttendancePresent = "참석"
+	RaidAttendanceAbsent  = "불참"
+	RaidAttendanceWalkIn  = "미신청 참석"
+)
+
+type RaidAttendance struct {
+	gorm.Model
+	RaidInfoID uint
+	RaidInfo   RaidInfo `gorm:"foreignKey:RaidInfoID"`
+
+	MemberInfo
+	Status string
+}
+
+const (
+	RaidLedgerSignedUp      = "신청"
+	RaidLedgerAttended      = "참석"
+	RaidLedgerNoShow        = "불참"
+	RaidLedgerCanceledEarly = "조기취소"
+	RaidLedgerCanceledLate  = "지각취소"
+	RaidLedgerNotFormed     = "미편성"
+)
+
+type RaidAttendLedger struct {
+	gorm.Model
+	RaidScheduleID uint
+	RaidSchedule   RaidSchedule `gorm:"foreignKey:RaidScheduleID"`
+	StartTime      time.Time
+
+	MemberInfo
+	SignedUp bool
+	Outcome  string
+}
+
+type RaidReportPost struct {
+	gorm.Model
+	Week string
+}